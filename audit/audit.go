@@ -0,0 +1,163 @@
+// Package audit writes append-only, hash-chained records of transitions,
+// verifies that such a log was not tampered with and projects it into read
+// models.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// Record describes one transition.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Machine string    `json:"machine"`
+	// Key identifies the instance, it is optional
+	Key    string `json:"key,omitempty"`
+	From   string `json:"from"`
+	Symbol string `json:"symbol"`
+	To     string `json:"to"`
+}
+
+// Entry is a record as written to the log. Hash covers the sequence number,
+// the record and the hash of the previous entry, Signature is the HMAC of
+// the hash if the log is signed.
+type Entry struct {
+	Seq uint64 `json:"seq"`
+	Record
+	Prev      string `json:"prev"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// TamperError reports the first entry of a log that failed verification.
+type TamperError struct {
+	Seq    uint64
+	Reason string
+}
+
+func (e *TamperError) Error() string {
+	return fmt.Sprintf("audit entry %d: %s", e.Seq, e.Reason)
+}
+
+// Log appends entries as JSON lines to a writer. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	key  []byte
+	seq  uint64
+	prev string
+}
+
+// NewLog creates a new log writing to w. If key is not empty, every entry
+// is signed with an HMAC-SHA256 using the key. Use ResumeLog to append to
+// an existing log.
+func NewLog(w io.Writer, key []byte) *Log {
+	return &Log{enc: json.NewEncoder(w), key: key}
+}
+
+// ResumeLog verifies the existing log read from r like Verify and creates a
+// log writing to w that continues its sequence and hash chain, e.g. after a
+// restart with r and w being the same file opened for reading and appending.
+func ResumeLog(r io.Reader, w io.Writer, key []byte) (*Log, error) {
+	seq, prev, err := chain(r, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{enc: json.NewEncoder(w), key: key, seq: seq, prev: prev}, nil
+}
+
+// Append chains the record to the log and writes it.
+func (l *Log) Append(record Record) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := Entry{Seq: l.seq, Record: record, Prev: l.prev}
+	entry.Hash = hash(entry)
+	if len(l.key) > 0 {
+		entry.Signature = sign(l.key, entry.Hash)
+	}
+	if err := l.enc.Encode(entry); err != nil {
+		return Entry{}, err
+	}
+	l.seq++
+	l.prev = entry.Hash
+	return entry, nil
+}
+
+// Step executes a step on the machine and appends successful transitions.
+func (l *Log) Step(m *dfa.DFA, state, symbol string) (string, bool, error) {
+	next, ok, err := m.Step(state, symbol)
+	if err != nil || !ok {
+		return next, ok, err
+	}
+	_, err = l.Append(Record{
+		Time:    time.Now().UTC(),
+		Machine: m.Name,
+		From:    state,
+		Symbol:  symbol,
+		To:      next,
+	})
+	return next, ok, err
+}
+
+// Verify reads a log and checks the sequence, the hash chain and, if key is
+// not empty, the signatures. The first violation is returned as *TamperError.
+func Verify(r io.Reader, key []byte) error {
+	_, _, err := chain(r, key)
+	return err
+}
+
+// chain verifies a log like Verify and returns the sequence number and the
+// hash the next entry continues with.
+func chain(r io.Reader, key []byte) (uint64, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var seq uint64
+	prev := ""
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return seq, prev, &TamperError{Seq: seq, Reason: "malformed entry: " + err.Error()}
+		}
+		switch {
+		case entry.Seq != seq:
+			return seq, prev, &TamperError{Seq: seq, Reason: fmt.Sprintf("unexpected sequence number %d", entry.Seq)}
+		case entry.Prev != prev:
+			return seq, prev, &TamperError{Seq: seq, Reason: "broken hash chain"}
+		case entry.Hash != hash(entry):
+			return seq, prev, &TamperError{Seq: seq, Reason: "hash mismatch"}
+		case len(key) > 0 && !hmac.Equal([]byte(entry.Signature), []byte(sign(key, entry.Hash))):
+			return seq, prev, &TamperError{Seq: seq, Reason: "invalid signature"}
+		}
+		seq++
+		prev = entry.Hash
+	}
+	return seq, prev, scanner.Err()
+}
+
+// hash computes the chained hash of an entry.
+func hash(entry Entry) string {
+	data, _ := json.Marshal(struct {
+		Seq uint64 `json:"seq"`
+		Record
+		Prev string `json:"prev"`
+	}{entry.Seq, entry.Record, entry.Prev})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sign computes the HMAC signature of a hash.
+func sign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Projection maintains a read model from the entries of a log. Entries are
+// applied at least once, so projections should tolerate entries applied
+// again after a restart from an older checkpoint.
+type Projection interface {
+	Apply(entry Entry) error
+}
+
+// ProjectionFunc adapts a function to a Projection.
+type ProjectionFunc func(entry Entry) error
+
+// Apply calls the function.
+func (f ProjectionFunc) Apply(entry Entry) error {
+	return f(entry)
+}
+
+// Projector applies the entries of a log to projections, resuming after the
+// last applied entry.
+type Projector struct {
+	Projections []Projection
+	// Next is the sequence number of the next entry to apply, earlier
+	// entries are skipped
+	Next uint64
+	// Checkpoint is called with Next after every applied entry if set, e.g.
+	// to persist it for resuming
+	Checkpoint func(next uint64) error
+}
+
+// Run applies the entries of the log from Next on. It stops at the first
+// error, Next then points at the entry that failed.
+func (p *Projector) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("audit entry %d: %w", p.Next, err)
+		}
+		if entry.Seq < p.Next {
+			continue
+		}
+		for _, projection := range p.Projections {
+			if err := projection.Apply(entry); err != nil {
+				return fmt.Errorf("audit entry %d: %w", entry.Seq, err)
+			}
+		}
+		p.Next = entry.Seq + 1
+		if p.Checkpoint != nil {
+			if err := p.Checkpoint(p.Next); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// StateCounts counts the entries into every state per time window, e.g.
+// per hour. Entries applied again are counted again, so its checkpoint
+// should be saved together with the counts. It is safe for concurrent use.
+type StateCounts struct {
+	window time.Duration
+	mu     sync.RWMutex
+	counts map[time.Time]map[string]int
+}
+
+// NewStateCounts creates counts per window.
+func NewStateCounts(window time.Duration) *StateCounts {
+	return &StateCounts{window: window, counts: make(map[time.Time]map[string]int)}
+}
+
+// Apply counts the entry into its target state.
+func (c *StateCounts) Apply(entry Entry) error {
+	start := entry.Time.Truncate(c.window)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[start] == nil {
+		c.counts[start] = make(map[string]int)
+	}
+	c.counts[start][entry.To]++
+	return nil
+}
+
+// Count returns the number of entries into the state within the window
+// containing the time.
+func (c *StateCounts) Count(state string, at time.Time) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.counts[at.Truncate(c.window)][state]
+}
+
+// LatestStates tracks the latest state per instance key, records without
+// key are tracked by machine name. It is safe for concurrent use.
+type LatestStates struct {
+	mu     sync.RWMutex
+	states map[string]Entry
+}
+
+// NewLatestStates creates an empty read model.
+func NewLatestStates() *LatestStates {
+	return &LatestStates{states: make(map[string]Entry)}
+}
+
+// Apply records the target state of the entry unless a later entry of the
+// key was applied before.
+func (l *LatestStates) Apply(entry Entry) error {
+	key := entry.Key
+	if key == "" {
+		key = entry.Machine
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if latest, ok := l.states[key]; !ok || latest.Seq <= entry.Seq {
+		l.states[key] = entry
+	}
+	return nil
+}
+
+// State returns the latest state of the key and whether it is known.
+func (l *LatestStates) State(key string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.states[key]
+	return entry.To, ok
+}
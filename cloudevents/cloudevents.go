@@ -0,0 +1,156 @@
+// Package cloudevents emits the transitions and acceptances of runners as
+// CloudEvents in structured JSON mode, so event meshes can consume the
+// progress of workflows in a standard envelope.
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+const (
+	// SpecVersion is the CloudEvents version of the emitted events
+	SpecVersion = "1.0"
+	// TransitionType and AcceptanceType are the types of the emitted events
+	TransitionType = "io.gopherstate.transition"
+	AcceptanceType = "io.gopherstate.acceptance"
+)
+
+// Event is a CloudEvent, Data is encoded as JSON.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	Data            any       `json:"data,omitempty"`
+}
+
+// Transition is the data of a transition event.
+type Transition struct {
+	From   string `json:"from"`
+	Symbol string `json:"symbol"`
+	To     string `json:"to"`
+}
+
+// Acceptance is the data of an acceptance event, Path holds the states
+// entered since the last reset of the runner.
+type Acceptance struct {
+	State string   `json:"state"`
+	Path  []string `json:"path"`
+}
+
+// Sink receives the emitted events, e.g. to publish them to a broker.
+type Sink interface {
+	Send(event Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(event Event) error
+
+// Send calls the function.
+func (f SinkFunc) Send(event Event) error {
+	return f(event)
+}
+
+// WriterSink writes events as JSON lines. It is safe for concurrent use.
+type WriterSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterSink creates a sink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+// Send writes the event.
+func (s *WriterSink) Send(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
+
+// Tracer is a dfa.Tracer emitting an event for every transition and
+// acceptance of one runner instance. The source of the events is
+// "/machines/<machine>", their subject the instance and the state entered
+// is part of their data. The first error of the sink is kept (see Err),
+// later events are still sent.
+type Tracer struct {
+	sink     Sink
+	machine  string
+	instance string
+	clock    dfa.Clock
+	// created makes the IDs unique across tracers of the same instance
+	created int64
+	mu      sync.Mutex
+	seq     uint64
+	err     error
+}
+
+// NewTracer creates a tracer for the instance of the machine, attach it
+// with Runner.SetTracer.
+func NewTracer(sink Sink, machine, instance string) *Tracer {
+	return &Tracer{sink: sink, machine: machine, instance: instance, clock: dfa.SystemClock,
+		created: time.Now().UnixNano()}
+}
+
+// SetClock sets the clock of the event times, e.g. the clock of the runner.
+func (t *Tracer) SetClock(clock dfa.Clock) {
+	t.clock = clock
+}
+
+// Err returns the first error of the sink, nil if all events were sent.
+func (t *Tracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// OnStep emits a transition event.
+func (t *Tracer) OnStep(from, symbol, to string) {
+	t.emit(TransitionType, Transition{From: from, Symbol: symbol, To: to})
+}
+
+// OnReject does not emit an event.
+func (t *Tracer) OnReject(state, symbol string) {}
+
+// OnAccept emits an acceptance event.
+func (t *Tracer) OnAccept(path []string) {
+	acceptance := Acceptance{Path: path}
+	if len(path) > 0 {
+		acceptance.State = path[len(path)-1]
+	}
+	t.emit(AcceptanceType, acceptance)
+}
+
+// emit sends an event of the type with the data.
+func (t *Tracer) emit(eventType string, data any) {
+	t.mu.Lock()
+	t.seq++
+	event := Event{
+		SpecVersion:     SpecVersion,
+		ID:              fmt.Sprintf("%s-%d-%d", t.instance, t.created, t.seq),
+		Source:          "/machines/" + t.machine,
+		Type:            eventType,
+		Subject:         t.instance,
+		Time:            t.clock.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	t.mu.Unlock()
+	if err := t.sink.Send(event); err != nil {
+		t.mu.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mu.Unlock()
+	}
+}
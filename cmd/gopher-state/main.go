@@ -0,0 +1,237 @@
+// Command gopher-state checks machine definitions and replays event logs
+// against them.
+//
+// Usage:
+//
+//	gopher-state validate ./machines/...
+//	gopher-state replay [-time f] [-key f] [-symbol f] [-layout l] [-summary] machine.json events.ndjson
+//	gopher-state diff [-format dot|svg] old.json new.json
+//	gopher-state doc [-format markdown|html] [-o dir] machine.json ...
+//	gopher-state lint machine.json ...
+//
+// validate checks all JSON definitions of the given directories (a
+// trailing "/..." includes subdirectories) and exits with status 1 if any
+// issue was found.
+//
+// replay feeds the events of a newline delimited JSON file to the machine,
+// one runner per key in the order of their times (see dfa.Replay), and
+// prints the outcome of every instance, or with -summary the aggregated
+// outcome (see dfa.Summarize). The flags name the fields of the events,
+// nested fields are addressed with dots; files ending in .csv are read as
+// "time,key,symbol" records instead. The machine is set up with its
+// profile (see dfa.DFA.SetUp), steps are logged to standard error.
+//
+// diff prints both versions of a machine as one graph in which added
+// states and transitions are green, removed ones red and states whose Final
+// flag changed orange (see dfa.ToDOTDiff), e.g. to attach it to a pull
+// request. The default format is DOT, svg pipes it through "dot -Tsvg",
+// which requires Graphviz. The exit status is 1 if the versions differ.
+//
+// doc generates a documentation page per machine (see dfa.DFA.Markdown and
+// dfa.DFA.HTML) and prints it, or with -o writes it to the directory as
+// <machine>.md or <machine>.html.
+//
+// lint checks the machines with the default lint rules (see dfa.Lint),
+// prints the findings and exits with status 1 if there are any.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+const usage = `usage: gopher-state validate <dir>[/...] ...
+       gopher-state replay [flags] <machine.json> <events>
+       gopher-state diff [-format dot|svg] <old.json> <new.json>
+       gopher-state doc [-format markdown|html] [-o dir] <machine.json> ...
+       gopher-state lint <machine.json> ...`
+
+func main() {
+	if len(os.Args) < 2 {
+		fail(2, usage)
+	}
+	switch os.Args[1] {
+	case "validate":
+		validate(os.Args[2:])
+	case "replay":
+		replay(os.Args[2:])
+	case "diff":
+		diff(os.Args[2:])
+	case "doc":
+		doc(os.Args[2:])
+	case "lint":
+		lint(os.Args[2:])
+	default:
+		fail(2, usage)
+	}
+}
+
+func validate(args []string) {
+	if len(args) == 0 {
+		fail(2, usage)
+	}
+	files, err := dfa.DefinitionFiles(args...)
+	if err != nil {
+		fail(2, err)
+	}
+	report := dfa.CheckFiles(files)
+	fmt.Println(report)
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+func replay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	mapping := dfa.DefaultEventMapping
+	flags.StringVar(&mapping.Time, "time", mapping.Time, "field of the event time")
+	flags.StringVar(&mapping.Key, "key", mapping.Key, "field of the instance key")
+	flags.StringVar(&mapping.Symbol, "symbol", mapping.Symbol, "field of the symbol")
+	flags.StringVar(&mapping.TimeLayout, "layout", "", "layout of string times, RFC 3339 by default")
+	summary := flags.Bool("summary", false, "print the aggregated outcome instead of every instance")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		fail(2, usage)
+	}
+	m, err := readMachine(flags.Arg(0))
+	if err != nil {
+		fail(2, err)
+	}
+	m.SetUp(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	events, err := readEvents(flags.Arg(1), mapping)
+	if err != nil {
+		fail(2, err)
+	}
+	results := dfa.Replay(m, events, time.Time{})
+	if *summary {
+		fmt.Print(dfa.Summarize(results))
+		return
+	}
+	for _, result := range results {
+		fmt.Printf("%s\t%s\taccepted=%t\tsteps=%d\trejections=%d\n", result.Key, result.State,
+			result.Accepted, result.Steps, len(result.Rejections))
+		if result.Err != nil {
+			fmt.Printf("%s\terror: %v\n", result.Key, result.Err)
+		}
+	}
+}
+
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "dot", "output format, dot or svg")
+	flags.Parse(args)
+	if flags.NArg() != 2 || (*format != "dot" && *format != "svg") {
+		fail(2, usage)
+	}
+	old, err := readMachine(flags.Arg(0))
+	if err != nil {
+		fail(2, err)
+	}
+	updated, err := readMachine(flags.Arg(1))
+	if err != nil {
+		fail(2, err)
+	}
+	graph := dfa.ToDOTDiff(old, updated)
+	if *format == "dot" {
+		fmt.Print(graph)
+	} else {
+		cmd := exec.Command("dot", "-Tsvg")
+		cmd.Stdin = bytes.NewBufferString(graph)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fail(2, fmt.Errorf("dot: %w", err))
+		}
+	}
+	if !dfa.DiffMachines(old, updated).Empty() {
+		os.Exit(1)
+	}
+}
+
+func doc(args []string) {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	format := flags.String("format", "markdown", "output format, markdown or html")
+	dir := flags.String("o", "", "directory to write the pages to instead of printing them")
+	flags.Parse(args)
+	if flags.NArg() == 0 || (*format != "markdown" && *format != "html") {
+		fail(2, usage)
+	}
+	for _, path := range flags.Args() {
+		m, err := readMachine(path)
+		if err != nil {
+			fail(2, err)
+		}
+		page, extension := m.Markdown(), ".md"
+		if *format == "html" {
+			page, extension = m.HTML(), ".html"
+		}
+		if *dir == "" {
+			fmt.Print(page)
+			continue
+		}
+		name := filepath.Join(*dir, url.PathEscape(m.Name)+extension)
+		if err := os.WriteFile(name, []byte(page), 0o644); err != nil {
+			fail(2, err)
+		}
+	}
+}
+
+func lint(args []string) {
+	if len(args) == 0 {
+		fail(2, usage)
+	}
+	found := false
+	for _, path := range args {
+		m, err := readMachine(path)
+		if err != nil {
+			fail(2, err)
+		}
+		for _, finding := range dfa.Lint(m) {
+			fmt.Printf("%s: %s\n", path, finding)
+			found = true
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// readMachine reads a JSON machine definition.
+func readMachine(path string) (*dfa.DFA, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := dfa.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// readEvents reads the events of a CSV or NDJSON file.
+func readEvents(path string, mapping dfa.EventMapping) ([]dfa.Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.HasSuffix(path, ".csv") {
+		return dfa.ReadEvents(file)
+	}
+	return dfa.ReadEventsNDJSON(file, mapping)
+}
+
+func fail(status int, message any) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(status)
+}
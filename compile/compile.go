@@ -0,0 +1,144 @@
+// Package compile compiles regular expressions over symbols into DFAs.
+//
+// Symbols are separated by whitespace, e.g. "login (retry)* (success|lockout)".
+// Patterns support concatenation, alternation (|), grouping with
+// parentheses and the repetitions *, + and ?.
+package compile
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/breskos/gopher-state/dfa"
+	"github.com/breskos/gopher-state/nfa"
+)
+
+// Compile compiles the pattern into a minimal DFA named after the pattern.
+// The states are named s0, s1, ... in breadth-first order from the start.
+func Compile(pattern string) (*dfa.DFA, error) {
+	automaton, err := CompileNFA(pattern)
+	if err != nil {
+		return nil, err
+	}
+	minimal := automaton.ToDFA().Minimize()
+	return rename(minimal), nil
+}
+
+// MustCompile is like Compile but panics if the pattern can not be parsed.
+func MustCompile(pattern string) *dfa.DFA {
+	m, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// CompileNFA compiles the pattern into an NFA using Thompson's construction.
+func CompileNFA(pattern string) (*nfa.NFA, error) {
+	tree, err := parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{automaton: nfa.NewNFA(pattern)}
+	start, end := b.build(tree)
+	end.SetFinal(true)
+	b.automaton.SetStart(start.Name)
+	return b.automaton, nil
+}
+
+// builder creates the states of Thompson's construction.
+type builder struct {
+	automaton *nfa.NFA
+	count     int
+}
+
+// state creates a new state.
+func (b *builder) state() *nfa.State {
+	s := nfa.NewState("n" + strconv.Itoa(b.count))
+	b.count++
+	b.automaton.SetState(s)
+	return s
+}
+
+// build creates the fragment of a node and returns its start and end state.
+func (b *builder) build(n *node) (*nfa.State, *nfa.State) {
+	start, end := b.state(), b.state()
+	switch n.kind {
+	case symbolNode:
+		start.AddTransition(end, n.symbol)
+	case emptyNode:
+		start.AddEpsilon(end)
+	case concatNode:
+		current := start
+		for _, child := range n.children {
+			s, e := b.build(child)
+			current.AddEpsilon(s)
+			current = e
+		}
+		current.AddEpsilon(end)
+	case altNode:
+		for _, child := range n.children {
+			s, e := b.build(child)
+			start.AddEpsilon(s)
+			e.AddEpsilon(end)
+		}
+	case starNode, plusNode, optionalNode:
+		s, e := b.build(n.children[0])
+		start.AddEpsilon(s)
+		e.AddEpsilon(end)
+		if n.kind != plusNode {
+			start.AddEpsilon(end)
+		}
+		if n.kind != optionalNode {
+			e.AddEpsilon(s)
+		}
+	}
+	return start, end
+}
+
+// rename names the states of a DFA s0, s1, ... in breadth-first order.
+func rename(m *dfa.DFA) *dfa.DFA {
+	names := make(map[string]string)
+	var order []string
+	queue := []string{m.GetStart()}
+	names[m.GetStart()] = "s0"
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		order = append(order, name)
+		state := m.GetState(name)
+		if state == nil {
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			to := state.Transitions[symbol]
+			if _, ok := names[to]; !ok {
+				names[to] = "s" + strconv.Itoa(len(names))
+				queue = append(queue, to)
+			}
+		}
+	}
+	renamed := dfa.NewDFA(m.Name)
+	for _, name := range order {
+		state := dfa.NewState(names[name])
+		if original := m.GetState(name); original != nil {
+			state.SetFinal(original.Final)
+			for symbol, to := range original.Transitions {
+				state.Transitions[symbol] = names[to]
+			}
+		}
+		renamed.SetState(state)
+	}
+	renamed.SetStart("s0")
+	return renamed
+}
+
+// sortedSymbols returns the symbols of the state's transitions in order.
+func sortedSymbols(state *dfa.State) []string {
+	symbols := make([]string, 0, len(state.Transitions))
+	for symbol := range state.Transitions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
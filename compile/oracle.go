@@ -0,0 +1,79 @@
+package compile
+
+import "strings"
+
+// Mismatch is an input the compiled DFA and the NFA of a pattern disagree on.
+type Mismatch struct {
+	Input []string
+	// DFA and NFA tell whether they accept the input
+	DFA bool
+	NFA bool
+}
+
+func (m Mismatch) String() string {
+	verdict := func(accepted bool) string {
+		if accepted {
+			return "accepts"
+		}
+		return "rejects"
+	}
+	return "\"" + strings.Join(m.Input, " ") + "\": dfa " + verdict(m.DFA) + ", nfa " + verdict(m.NFA)
+}
+
+// Oracle compiles the pattern and checks every input of up to n symbols
+// against a simulation of its NFA, returning the inputs they disagree on
+// in length-lexicographic order. The inputs are built from the alphabet,
+// the symbols of the pattern by default. The number of inputs grows
+// exponentially with n, so it is meant for small alphabets and lengths.
+func Oracle(pattern string, n int, alphabet ...string) ([]Mismatch, error) {
+	automaton, err := CompileNFA(pattern)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(alphabet) == 0 {
+		alphabet = automaton.GetSymbols()
+	}
+	// frontier holds the inputs of the current length with the DFA state
+	// (empty once rejected) and the NFA states they lead to
+	type input struct {
+		symbols []string
+		state   string
+		states  []string
+	}
+	var mismatches []Mismatch
+	frontier := []input{{state: m.GetStart(), states: automaton.Closure([]string{automaton.GetStart()})}}
+	for length := 0; length <= n; length++ {
+		var next []input
+		for _, in := range frontier {
+			state := m.GetState(in.state)
+			accepted := state != nil && state.Final
+			expected := false
+			for _, name := range in.states {
+				if s := automaton.GetState(name); s != nil && s.IsFinal() {
+					expected = true
+					break
+				}
+			}
+			if accepted != expected {
+				mismatches = append(mismatches, Mismatch{Input: in.symbols, DFA: accepted, NFA: expected})
+			}
+			if length == n {
+				continue
+			}
+			for _, symbol := range alphabet {
+				to := ""
+				if state != nil {
+					to = state.Transitions[symbol]
+				}
+				symbols := append(append(make([]string, 0, length+1), in.symbols...), symbol)
+				next = append(next, input{symbols: symbols, state: to, states: automaton.Step(in.states, symbol)})
+			}
+		}
+		frontier = next
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,184 @@
+package compile
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const operators = "()|*+?"
+
+// token is a lexical token of a pattern.
+type token struct {
+	// kind is the operator character or 0 for a symbol
+	kind   byte
+	symbol string
+	pos    int
+}
+
+// node is a node of the syntax tree of a pattern.
+type node struct {
+	// kind is one of the node kinds below
+	kind     int
+	symbol   string
+	children []*node
+}
+
+const (
+	symbolNode = iota
+	emptyNode
+	concatNode
+	altNode
+	starNode
+	plusNode
+	optionalNode
+)
+
+// lex splits a pattern into symbols and operators. Symbols are separated
+// by whitespace or operators.
+func lex(pattern string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, token{symbol: pattern[start:end], pos: start})
+			start = -1
+		}
+	}
+	for i, r := range pattern {
+		switch {
+		case unicode.IsSpace(r):
+			flush(i)
+		case r < 128 && strings.IndexByte(operators, byte(r)) >= 0:
+			flush(i)
+			tokens = append(tokens, token{kind: byte(r), pos: i})
+		case start < 0:
+			start = i
+		}
+	}
+	flush(len(pattern))
+	return tokens
+}
+
+// parser is a recursive descent parser of patterns.
+type parser struct {
+	tokens []token
+	pos    int
+	length int
+}
+
+// parse parses a pattern into its syntax tree.
+func parse(pattern string) (*node, error) {
+	p := &parser{tokens: lex(pattern), length: len(pattern)}
+	tree, err := p.alternation()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected '%c'", p.tokens[p.pos].kind)
+	}
+	return tree, nil
+}
+
+// alternation := concatenation ('|' concatenation)*
+func (p *parser) alternation() (*node, error) {
+	first, err := p.concatenation()
+	if err != nil {
+		return nil, err
+	}
+	alternatives := []*node{first}
+	for p.peek() == '|' {
+		p.pos++
+		next, err := p.concatenation()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, next)
+	}
+	if len(alternatives) == 1 {
+		return first, nil
+	}
+	return &node{kind: altNode, children: alternatives}, nil
+}
+
+// concatenation := repetition*
+func (p *parser) concatenation() (*node, error) {
+	var parts []*node
+	for p.pos < len(p.tokens) && p.peek() != '|' && p.peek() != ')' {
+		part, err := p.repetition()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	switch len(parts) {
+	case 0:
+		return &node{kind: emptyNode}, nil
+	case 1:
+		return parts[0], nil
+	}
+	return &node{kind: concatNode, children: parts}, nil
+}
+
+// repetition := atom ('*' | '+' | '?')*
+func (p *parser) repetition() (*node, error) {
+	atom, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case '*':
+			atom = &node{kind: starNode, children: []*node{atom}}
+		case '+':
+			atom = &node{kind: plusNode, children: []*node{atom}}
+		case '?':
+			atom = &node{kind: optionalNode, children: []*node{atom}}
+		default:
+			return atom, nil
+		}
+		p.pos++
+	}
+}
+
+// atom := symbol | '(' alternation ')'
+func (p *parser) atom() (*node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("unexpected end of pattern")
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case 0:
+		p.pos++
+		return &node{kind: symbolNode, symbol: t.symbol}, nil
+	case '(':
+		p.pos++
+		inner, err := p.alternation()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return nil, p.errorf("unexpected '%c'", t.kind)
+}
+
+// peek returns the operator of the current token, 0 for symbols and the end.
+func (p *parser) peek() byte {
+	if p.pos >= len(p.tokens) {
+		return 0
+	}
+	return p.tokens[p.pos].kind
+}
+
+// errorf returns an error at the position of the current token.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	pos := p.length
+	if p.pos < len(p.tokens) {
+		pos = p.tokens[p.pos].pos
+	}
+	return fmt.Errorf("compile: %s at position %d", fmt.Sprintf(format, args...), pos)
+}
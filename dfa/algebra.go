@@ -0,0 +1,89 @@
+package dfa
+
+// Union returns a new DFA accepting every word accepted by a or b.
+func Union(a, b *DFA) *DFA {
+	return product("union("+a.Name+","+b.Name+")", a, b, func(p, q bool) bool { return p || q })
+}
+
+// Intersect returns a new DFA accepting every word accepted by a and b.
+func Intersect(a, b *DFA) *DFA {
+	return product("intersect("+a.Name+","+b.Name+")", a, b, func(p, q bool) bool { return p && q })
+}
+
+// Concat returns a new DFA accepting every word of a followed by a word of b.
+func Concat(a, b *DFA) *DFA {
+	return concat("concat("+a.Name+","+b.Name+")", a, b)
+}
+
+// Complement returns a new DFA accepting every word over the alphabet of
+// the DFA (see GetAlphabet) that the DFA does not accept. Missing
+// transitions are completed by a non-final sink state which becomes final.
+func (m *DFA) Complement() *DFA {
+	next := func(key, symbol string) []string {
+		if to, ok := delta(m, key, symbol); ok {
+			return []string{to}
+		}
+		return []string{deadKey}
+	}
+	final := func(key string) bool {
+		return !isFinal(m, key)
+	}
+	complement := determinize("complement("+m.Name+")", m.stateNamer(), []string{m.Start}, m.GetAlphabet(), next, final, deadLabel)
+	complement.SetAlphabet(m.GetAlphabet())
+	return complement
+}
+
+// SetAlphabet declares the alphabet of the DFA, which may contain symbols
+// without any transition.
+func (m *DFA) SetAlphabet(symbols []string) {
+	m.Alphabet = append([]string{}, symbols...)
+}
+
+// GetAlphabet returns the declared alphabet or, if none was declared, the
+// sorted distinct symbols used by the transitions.
+func (m *DFA) GetAlphabet() []string {
+	if m.Alphabet != nil {
+		return m.Alphabet
+	}
+	return alphabetOf(m)
+}
+
+// product executes the product construction of a and b, a pair of states
+// being final if final reports so for the finality of both states.
+// Missing transitions lead into an implicit dead state, pairs that can not
+// become final anymore are dropped.
+func product(name string, a, b *DFA, final func(p, q bool) bool) *DFA {
+	deadA := !final(false, false) && !final(false, true)
+	deadB := !final(false, false) && !final(true, false)
+	step := func(m *DFA, state, symbol string) string {
+		if to, ok := delta(m, state, symbol); ok {
+			return to
+		}
+		return deadKey
+	}
+	next := func(key, symbol string) []string {
+		p, q := unpairKey(key)
+		toA, toB := step(a, p, symbol), step(b, q, symbol)
+		if (toA == deadKey && (toB == deadKey || deadA)) || (toB == deadKey && deadB) {
+			return nil
+		}
+		return []string{pairKey(toA, toB)}
+	}
+	isFinalPair := func(key string) bool {
+		p, q := unpairKey(key)
+		return final(isFinal(a, p), isFinal(b, q))
+	}
+	start := []string{pairKey(a.Start, b.Start)}
+	return determinize(name, a.stateNamer(), start, alphabetOf(a, b), next, isFinalPair, func(key string) string {
+		p, q := unpairKey(key)
+		return "(" + deadLabel(p) + "," + deadLabel(q) + ")"
+	})
+}
+
+// deadLabel renders the implicit dead state as "sink".
+func deadLabel(key string) string {
+	if key == deadKey {
+		return "sink"
+	}
+	return key
+}
@@ -0,0 +1,181 @@
+package dfa
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// ArchiveVersion is the version of the archive format written by Archive.
+const ArchiveVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+
+	errArchiveVersion = "unsupported archive version"
+	errNoManifest     = "archive has no manifest"
+	errChecksum       = "checksum mismatch"
+	errMissingFile    = "archive misses file"
+	errUnlistedFile   = "archive file not in manifest"
+)
+
+// Bundle is the content of an archive, e.g. to promote machines between
+// environments or to attach them to a support request.
+type Bundle struct {
+	// Definitions holds the machine and the submachines it embeds, load
+	// them with a Registry
+	Definitions []*Definition
+	// Metadata holds free-form values, e.g. the environment or a commit
+	Metadata map[string]string
+	// Instances holds runner snapshots by instance key, it is optional
+	Instances map[string]RunnerSnapshot
+}
+
+// manifest lists the files of an archive with their SHA-256 checksums.
+type manifest struct {
+	Version  int               `json:"version"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Files    map[string]string `json:"files"`
+}
+
+// Archive writes the bundle as a tar archive: a manifest with the version,
+// the metadata and the checksums of all other files, every definition in
+// the JSON and the gob encoding and every instance snapshot in the JSON
+// encoding (see Codec).
+func (b *Bundle) Archive(w io.Writer) error {
+	files := make(map[string][]byte)
+	for _, definition := range b.Definitions {
+		name := "machines/" + url.PathEscape(definition.Name)
+		for extension, codec := range map[string]Codec{".json": JSONCodec, ".gob": GobCodec} {
+			var buf bytes.Buffer
+			if err := codec.Encode(&buf, definition); err != nil {
+				return fmt.Errorf("machine '%s': %w", definition.Name, err)
+			}
+			files[name+extension] = buf.Bytes()
+		}
+	}
+	for key, snapshot := range b.Instances {
+		var buf bytes.Buffer
+		if err := JSONCodec.EncodeSnapshot(&buf, snapshot); err != nil {
+			return fmt.Errorf("instance '%s': %w", key, err)
+		}
+		files["instances/"+url.PathEscape(key)+".json"] = buf.Bytes()
+	}
+	m := manifest{Version: ArchiveVersion, Metadata: b.Metadata, Files: make(map[string]string, len(files))}
+	for name, data := range files {
+		m.Files[name] = checksum(data)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	if err := writeFile(tw, manifestFile, data); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(files) {
+		if err := writeFile(tw, name, files[name]); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// Unarchive reads a bundle written by Archive. An error is returned if the
+// version is not supported, a file is missing or not listed in the
+// manifest, or a checksum does not match.
+func Unarchive(r io.Reader) (*Bundle, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = data
+	}
+	data, ok := files[manifestFile]
+	if !ok {
+		return nil, errors.New(errNoManifest)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestFile, err)
+	}
+	if m.Version != ArchiveVersion {
+		return nil, fmt.Errorf("%s %d", errArchiveVersion, m.Version)
+	}
+	for _, name := range sortedKeys(m.Files) {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s '%s'", errMissingFile, name)
+		}
+		if checksum(data) != m.Files[name] {
+			return nil, fmt.Errorf("%s of '%s'", errChecksum, name)
+		}
+	}
+	bundle := &Bundle{Metadata: m.Metadata}
+	for _, name := range sortedKeys(files) {
+		if name == manifestFile {
+			continue
+		}
+		if _, ok := m.Files[name]; !ok {
+			return nil, fmt.Errorf("%s '%s'", errUnlistedFile, name)
+		}
+		dir, file := path.Split(name)
+		switch {
+		case dir == "machines/" && strings.HasSuffix(file, ".json"):
+			definition, err := JSONCodec.Decode(bytes.NewReader(files[name]))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			bundle.Definitions = append(bundle.Definitions, definition)
+		case dir == "instances/" && strings.HasSuffix(file, ".json"):
+			key, err := url.PathUnescape(strings.TrimSuffix(file, ".json"))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			snapshot, err := JSONCodec.DecodeSnapshot(bytes.NewReader(files[name]))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if bundle.Instances == nil {
+				bundle.Instances = make(map[string]RunnerSnapshot)
+			}
+			bundle.Instances[key] = snapshot
+		}
+	}
+	return bundle, nil
+}
+
+// writeFile writes a file to the tar archive.
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Unix(0, 0)}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// checksum returns the hex encoded SHA-256 checksum of the data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
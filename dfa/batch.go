@@ -0,0 +1,150 @@
+package dfa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchRules are the lint rules CheckFiles applies in addition to Validate.
+var batchRules = []Rule{ShadowedRule, StuckRule}
+
+// FileCheck holds the issues found in one definition file.
+type FileCheck struct {
+	Path   string
+	Issues []string
+}
+
+// BatchReport is the consolidated result of CheckFiles.
+type BatchReport struct {
+	Files []FileCheck
+	// Issues holds the issues between the machines, like unknown submachines
+	Issues []string
+}
+
+// OK tests if no issue was found.
+func (r *BatchReport) OK() bool {
+	if len(r.Issues) > 0 {
+		return false
+	}
+	for _, file := range r.Files {
+		if len(file.Issues) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report with one line per issue.
+func (r *BatchReport) String() string {
+	var lines []string
+	failed := 0
+	for _, file := range r.Files {
+		if len(file.Issues) > 0 {
+			failed++
+		}
+		for _, issue := range file.Issues {
+			lines = append(lines, file.Path+": "+issue)
+		}
+	}
+	lines = append(lines, r.Issues...)
+	lines = append(lines, fmt.Sprintf("%d file(s) checked, %d with issues, %d cross-reference issue(s)",
+		len(r.Files), failed, len(r.Issues)))
+	return strings.Join(lines, "\n")
+}
+
+// CheckFiles validates definition files as a pre-merge gate: every file is
+// checked against the schema (ValidateDefinition), loaded, validated
+// (Validate) and linted (ShadowedRule, StuckRule), then the submachine
+// references between all machines are resolved with a Registry.
+func CheckFiles(paths []string) *BatchReport {
+	report := &BatchReport{}
+	registry := NewRegistry()
+	for _, path := range paths {
+		check := FileCheck{Path: path}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			check.Issues = append(check.Issues, err.Error())
+			report.Files = append(report.Files, check)
+			continue
+		}
+		for _, err := range ValidateDefinition(raw) {
+			check.Issues = append(check.Issues, err.Error())
+		}
+		definition, err := JSONCodec.Decode(strings.NewReader(string(raw)))
+		if err != nil {
+			// the schema errors already describe why it can not be decoded
+			if len(check.Issues) == 0 {
+				check.Issues = append(check.Issues, err.Error())
+			}
+			report.Files = append(report.Files, check)
+			continue
+		}
+		if err := registry.Add(definition); err != nil {
+			check.Issues = append(check.Issues, err.Error())
+			report.Files = append(report.Files, check)
+			continue
+		}
+		// submachines are resolved below, the machine itself is checked alone
+		m, err := fromDefinition(definition, func(name string) *DFA { return NewDFA(name) })
+		if err != nil {
+			if !covered(check.Issues, err) {
+				check.Issues = append(check.Issues, err.Error())
+			}
+			report.Files = append(report.Files, check)
+			continue
+		}
+		for _, problem := range m.Validate() {
+			// dangling transitions are reported by ValidateDefinition
+			if problem.Kind != DanglingTransition {
+				check.Issues = append(check.Issues, problem.Message)
+			}
+		}
+		for _, finding := range Lint(m, batchRules...) {
+			check.Issues = append(check.Issues, finding.String())
+		}
+		report.Files = append(report.Files, check)
+	}
+	if _, err := registry.Load(); err != nil {
+		report.Issues = append(report.Issues, err.Error())
+	}
+	return report
+}
+
+// covered tests if one of the issues already reports the error, e.g. a
+// schema error about a duplicate state.
+func covered(issues []string, err error) bool {
+	for _, issue := range issues {
+		if strings.Contains(issue, err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefinitionFiles returns the "*.json" files of the directories, sorted.
+// A directory ending in "/..." is searched recursively.
+func DefinitionFiles(dirs ...string) ([]string, error) {
+	var files []string
+	for _, dir := range dirs {
+		if root, ok := strings.CutSuffix(dir, "/..."); ok {
+			err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() && filepath.Ext(path) == ".json" {
+					files = append(files, path)
+				}
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return sortedKeys(toSet(files)), nil
+}
@@ -0,0 +1,19 @@
+package dfa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckFilesDuplicateState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duplicate.json")
+	definition := `{"name":"duplicate","start":"s","states":[{"name":"s","final":true},{"name":"s"}]}`
+	if err := os.WriteFile(path, []byte(definition), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report := CheckFiles([]string{path})
+	if report.OK() || len(report.Files) != 1 || len(report.Files[0].Issues) == 0 {
+		t.Fatalf("expected issues, got %s", report)
+	}
+}
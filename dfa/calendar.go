@@ -0,0 +1,129 @@
+package dfa
+
+import (
+	"fmt"
+	"time"
+)
+
+const errUnknownCalendar = "unknown calendar"
+
+// Calendar computes when a timeout measured on it expires, e.g. counting
+// only business days.
+type Calendar interface {
+	// Deadline returns the time at which the duration elapsed from start
+	Deadline(start time.Time, after time.Duration) time.Time
+}
+
+// BusinessCalendar counts only the time on business days in its location,
+// so a timeout of 48 hours entered on a Friday at 15:00 expires on Tuesday
+// at 15:00. Days are business days unless they are weekend days or
+// holidays.
+type BusinessCalendar struct {
+	// Location determines where days start, UTC if it is nil
+	Location *time.Location
+	// Weekend holds the days off every week, Saturday and Sunday if empty
+	Weekend []time.Weekday
+	// Holidays holds the days off by date, their time of day and location
+	// are ignored
+	Holidays []time.Time
+}
+
+// maxCalendarDays bounds the days searched for business time, so calendars
+// without business days do not loop forever.
+const maxCalendarDays = 100 * 366
+
+// Deadline returns the time at which the duration of business time elapsed
+// from start.
+func (c *BusinessCalendar) Deadline(start time.Time, after time.Duration) time.Time {
+	location := c.Location
+	if location == nil {
+		location = time.UTC
+	}
+	t := start.In(location)
+	for day := 0; day < maxCalendarDays; day++ {
+		year, month, date := t.Date()
+		midnight := time.Date(year, month, date+1, 0, 0, 0, 0, location)
+		if c.business(t) {
+			left := midnight.Sub(t)
+			if after <= left {
+				return t.Add(after)
+			}
+			after -= left
+		}
+		t = midnight
+	}
+	return t
+}
+
+// business tests if the day of the time is a business day.
+func (c *BusinessCalendar) business(t time.Time) bool {
+	weekend := c.Weekend
+	if len(weekend) == 0 {
+		weekend = []time.Weekday{time.Saturday, time.Sunday}
+	}
+	for _, day := range weekend {
+		if t.Weekday() == day {
+			return false
+		}
+	}
+	year, month, date := t.Date()
+	for _, holiday := range c.Holidays {
+		// the date as given, converting it could shift it to another day
+		y, m, d := holiday.Date()
+		if y == year && m == month && d == date {
+			return false
+		}
+	}
+	return true
+}
+
+// SetCalendarTimeout sets a timeout like SetTimeout whose duration is
+// measured on the named calendar of the Runner (see Runner.SetCalendar),
+// e.g. 48 hours on a BusinessCalendar to escalate after two business days.
+func (s *State) SetCalendarTimeout(after time.Duration, symbol, calendar string) {
+	s.SetTimeout(after, symbol)
+	if s.timeout != nil {
+		s.timeout.calendar = calendar
+	}
+}
+
+// TimeoutCalendar returns the name of the calendar the timeout of the
+// state is measured on, empty if it is measured in real time.
+func (s *State) TimeoutCalendar() string {
+	if s.timeout == nil {
+		return ""
+	}
+	return s.timeout.calendar
+}
+
+// SetCalendar registers the calendar under the name for the timeouts of the
+// runner, its submachines and the shadow. Passing nil removes it.
+func (r *Runner) SetCalendar(name string, calendar Calendar) {
+	if r.calendars == nil {
+		r.calendars = make(map[string]Calendar)
+	}
+	if calendar == nil {
+		delete(r.calendars, name)
+	} else {
+		r.calendars[name] = calendar
+	}
+	for level := r.inner; level != nil; level = level.inner {
+		level.calendars = r.calendars
+	}
+	if r.shadow != nil {
+		r.shadow.SetCalendar(name, calendar)
+	}
+}
+
+// deadline returns the time the timeout of the current state expires. An
+// error is returned if its calendar is not registered.
+func (r *Runner) deadline(timeout *timeout) (time.Time, error) {
+	if timeout.calendar == "" {
+		return r.entered.Add(timeout.after), nil
+	}
+	calendar, ok := r.calendars[timeout.calendar]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s '%s'", errUnknownCalendar, timeout.calendar)
+	}
+	return calendar.Deadline(r.entered, timeout.after), nil
+}
@@ -0,0 +1,21 @@
+package dfa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessCalendarHolidayWestOfUTC(t *testing.T) {
+	location := time.FixedZone("UTC-5", -5*60*60)
+	calendar := &BusinessCalendar{
+		Location: location,
+		Holidays: []time.Time{time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)},
+	}
+	// Thursday Dec 24 is a business day, Friday Dec 25 a holiday
+	start := time.Date(2026, 12, 24, 12, 0, 0, 0, location)
+	got := calendar.Deadline(start, 24*time.Hour)
+	want := time.Date(2026, 12, 28, 12, 0, 0, 0, location)
+	if !got.Equal(want) {
+		t.Fatalf("deadline %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+)
+
+// checkEvery is the number of work units between two context checks.
+const checkEvery = 1024
+
+// Canceled reports an analysis aborted by its context. Done counts the
+// completed units of work out of at most Total, e.g. indexed states.
+type Canceled struct {
+	Op    string
+	Done  int64
+	Total int64
+	Err   error
+}
+
+func (e *Canceled) Error() string {
+	return fmt.Sprintf("%s canceled after %d of %d: %v", e.Op, e.Done, e.Total, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e *Canceled) Unwrap() error {
+	return e.Err
+}
+
+// SetProgress sets a function called with the completed and total units
+// of work while the DFA is minimized or replayed (see Minimize and Replay),
+// e.g. to show a progress bar. It is called once more when the work is
+// done. Passing nil removes it.
+func (m *DFA) SetProgress(progress func(done, total int64)) {
+	m.progress = progress
+}
+
+// report calls the progress function if it is set.
+func report(progress func(done, total int64), done, total int64) {
+	if progress != nil {
+		progress(done, total)
+	}
+}
+
+// canceled returns *Canceled if the context is done, nil if it is not or
+// the context is nil.
+func canceled(ctx context.Context, op string, done, total int64) error {
+	if ctx == nil || ctx.Err() == nil {
+		return nil
+	}
+	return &Canceled{Op: op, Done: done, Total: total, Err: ctx.Err()}
+}
@@ -0,0 +1,93 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	errNoBranch   = "no branch taken at choice"
+	errRouteCycle = "automatic transitions form a cycle at state"
+)
+
+// SetChoice makes the state a choice pseudo-state. When a Runner enters
+// it, the transitions of the symbols are tried in order and the first one
+// whose guard passes (see SetGuard and SetGuardExpr) is taken right away
+// without consuming a token; a transition without a guard always passes
+// and serves as the else branch. Only Runner routes choices, Step and Run
+// treat the state like any other state. Passing no symbols turns the
+// choice into a normal state again.
+func (s *State) SetChoice(symbols ...string) {
+	if len(symbols) == 0 {
+		s.choice = nil
+		return
+	}
+	s.choice = append([]string{}, symbols...)
+}
+
+// Choice returns the symbols of the branches of a choice pseudo-state in
+// the order they are tried, nil for other states.
+func (s *State) Choice() []string {
+	return s.choice
+}
+
+// route leaves choice pseudo-states and takes completion transitions until
+// the runner settles in another state. Guards and flags are evaluated with
+// the context or, if it is nil, with a context carrying the variables and
+// the history of the runner. The runner stays in a choice if no branch
+// passes and an error is returned.
+func (r *Runner) route(ctx context.Context) error {
+	if ctx == nil {
+		ctx = r.context(context.Background())
+	}
+	// automatic transitions leading into each other in a cycle would never settle
+	for visited := 0; visited <= len(r.machine.States); visited++ {
+		state := r.machine.GetState(r.current)
+		if state == nil {
+			return nil
+		}
+		symbol := Completion
+		switch {
+		case state.choice != nil:
+			var ok bool
+			if symbol, ok = r.branch(ctx, state); !ok {
+				return fmt.Errorf("%s '%s'", errNoBranch, r.current)
+			}
+		case state.submachine != nil || !passes(ctx, state, Completion) || r.flagged(ctx, state, Completion):
+			return nil
+		}
+		ok, err := r.step(ctx, symbol)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if state.choice != nil {
+				return fmt.Errorf("%s '%s'", errNoBranch, r.current)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s '%s'", errRouteCycle, r.current)
+}
+
+// branch returns the symbol of the first branch of the choice whose
+// transition exists, is not gated behind a disabled flag and whose guard
+// passes.
+func (r *Runner) branch(ctx context.Context, state *State) (string, bool) {
+	for _, symbol := range state.choice {
+		if passes(ctx, state, symbol) && !r.flagged(ctx, state, symbol) {
+			return symbol, true
+		}
+	}
+	return "", false
+}
+
+// passes tests if the state has a transition for the symbol whose guard,
+// if any, passes.
+func passes(ctx context.Context, state *State, symbol string) bool {
+	if _, ok := state.Transitions[symbol]; !ok {
+		return false
+	}
+	guard, ok := state.guards[symbol]
+	return !ok || guard(ctx, symbol) == nil
+}
@@ -0,0 +1,39 @@
+package dfa
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// Classifier maps a raw input symbol to the symbol class used by the transitions.
+type Classifier func(symbol string) string
+
+// SetClassifier installs a classifier that is applied to every input symbol
+// of Step and Run before the transition lookup, so machines with an
+// effectively unbounded input alphabet can work on a small class alphabet.
+func (m *DFA) SetClassifier(classifier Classifier) {
+	m.classifier = classifier
+}
+
+// MapClassifier returns a classifier looking up symbols in the classes map
+// and returning fallback for every unknown symbol.
+func MapClassifier(classes map[string]string, fallback string) Classifier {
+	return func(symbol string) string {
+		if class, ok := classes[symbol]; ok {
+			return class
+		}
+		return fallback
+	}
+}
+
+// HashClassifier returns a classifier that hashes symbols into the given
+// number of buckets, named prefix followed by the bucket number. The number
+// of buckets is at least 1.
+func HashClassifier(prefix string, buckets uint32) Classifier {
+	buckets = max(buckets, 1)
+	return func(symbol string) string {
+		h := fnv.New32a()
+		h.Write([]byte(symbol))
+		return prefix + strconv.FormatUint(uint64(h.Sum32()%buckets), 10)
+	}
+}
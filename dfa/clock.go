@@ -0,0 +1,54 @@
+package dfa
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the time to runners, e.g. to expire state timeouts.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of the wall time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock runners use by default.
+var SystemClock Clock = systemClock{}
+
+// VirtualClock is a Clock that only moves when it is set or advanced, for
+// simulations, replays and tests. It is safe for concurrent use.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock creates a new virtual clock at the given time.
+func NewVirtualClock(now time.Time) *VirtualClock {
+	return &VirtualClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the time of the clock, it may also move backwards.
+func (c *VirtualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,272 @@
+package dfa
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+const errCorruptEncoding = "corrupt encoding"
+
+// Codec encodes and decodes machine definitions and runner snapshots, so
+// the representation can be traded between readability (JSON) and speed and
+// size (gob).
+type Codec interface {
+	Encode(w io.Writer, definition *Definition) error
+	Decode(r io.Reader) (*Definition, error)
+	EncodeSnapshot(w io.Writer, snapshot RunnerSnapshot) error
+	DecodeSnapshot(r io.Reader) (RunnerSnapshot, error)
+}
+
+var (
+	// JSONCodec encodes definitions as JSON, the format of Marshal.
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec encodes definitions with encoding/gob. Variables of snapshots
+	// have to be of types registered with gob, like the basic types.
+	GobCodec Codec = gobCodec{}
+)
+
+// Encode writes the definition of the DFA using the codec.
+func (m *DFA) Encode(w io.Writer, codec Codec) error {
+	return codec.Encode(w, m.Definition())
+}
+
+// Decode reads a DFA using the codec.
+func Decode(r io.Reader, codec Codec) (*DFA, error) {
+	definition, err := codec.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromDefinition(definition)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, definition *Definition) error {
+	return json.NewEncoder(w).Encode(definition)
+}
+
+func (jsonCodec) Decode(r io.Reader) (*Definition, error) {
+	var definition Definition
+	if err := json.NewDecoder(r).Decode(&definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+func (jsonCodec) EncodeSnapshot(w io.Writer, snapshot RunnerSnapshot) error {
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+func (jsonCodec) DecodeSnapshot(r io.Reader) (RunnerSnapshot, error) {
+	var snapshot RunnerSnapshot
+	err := json.NewDecoder(r).Decode(&snapshot)
+	return snapshot, err
+}
+
+type gobCodec struct{}
+
+// gobDefinition is the gob form of a definition. Maps are stored as sorted
+// slices because gob writes maps in iteration order, which would make the
+// encoding of the same definition differ between runs.
+type gobDefinition struct {
+	Name     string
+	Start    string
+	Alphabet []string
+	States   []gobState
+	// EntryPoints and ExitPoints are sorted key and value pairs
+	EntryPoints     [2][]string
+	ExitPoints      [2][]string
+	CompletionEvent string
+	Profile         *Profile
+}
+
+type gobState struct {
+	Name          string
+	Final         bool
+	Symbols       []string
+	Targets       []string
+	WeightSymbols []string
+	Weights       []float64
+	Submachine    string
+	Exit          string
+	Position      *Point
+	RouteSymbols  []string
+	Routes        [][]Point
+	GuardSymbols  []string
+	Guards        []string
+	AssignSymbols []string
+	Assign        []string
+	Choice        []string
+	Entries       [2][]string
+	Flags         [2][]string
+	Defer         []string
+	Join          *JoinDefinition
+	Timeout       *TimeoutDefinition
+}
+
+func (gobCodec) Encode(w io.Writer, definition *Definition) error {
+	encoded := gobDefinition{Name: definition.Name, Start: definition.Start, Alphabet: definition.Alphabet,
+		EntryPoints: pairs(definition.EntryPoints), ExitPoints: pairs(definition.ExitPoints),
+		CompletionEvent: definition.CompletionEvent, Profile: definition.Profile}
+	for _, state := range definition.States {
+		g := gobState{Name: state.Name, Final: state.Final, Submachine: state.Submachine, Exit: state.Exit,
+			Choice: state.Choice, Entries: pairs(state.Entries), Flags: pairs(state.Flags), Defer: state.Defer, Join: state.Join,
+			Timeout: state.Timeout}
+		for _, symbol := range sortedKeys(state.Transitions) {
+			g.Symbols = append(g.Symbols, symbol)
+			g.Targets = append(g.Targets, state.Transitions[symbol])
+		}
+		for _, symbol := range sortedKeys(state.Weights) {
+			g.WeightSymbols = append(g.WeightSymbols, symbol)
+			g.Weights = append(g.Weights, state.Weights[symbol])
+		}
+		g.Position = state.Position
+		for _, symbol := range sortedKeys(state.Routes) {
+			g.RouteSymbols = append(g.RouteSymbols, symbol)
+			g.Routes = append(g.Routes, state.Routes[symbol])
+		}
+		for _, symbol := range sortedKeys(state.Guards) {
+			g.GuardSymbols = append(g.GuardSymbols, symbol)
+			g.Guards = append(g.Guards, state.Guards[symbol])
+		}
+		for _, symbol := range sortedKeys(state.Assign) {
+			g.AssignSymbols = append(g.AssignSymbols, symbol)
+			g.Assign = append(g.Assign, state.Assign[symbol])
+		}
+		encoded.States = append(encoded.States, g)
+	}
+	return gob.NewEncoder(w).Encode(&encoded)
+}
+
+func (gobCodec) Decode(r io.Reader) (*Definition, error) {
+	var decoded gobDefinition
+	if err := gob.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	definition := &Definition{Name: decoded.Name, Start: decoded.Start, Alphabet: decoded.Alphabet,
+		CompletionEvent: decoded.CompletionEvent, Profile: decoded.Profile}
+	var ok bool
+	if definition.EntryPoints, ok = unpair(decoded.EntryPoints); !ok {
+		return nil, errors.New(errCorruptEncoding)
+	}
+	if definition.ExitPoints, ok = unpair(decoded.ExitPoints); !ok {
+		return nil, errors.New(errCorruptEncoding)
+	}
+	for _, g := range decoded.States {
+		state := StateDefinition{Name: g.Name, Final: g.Final, Submachine: g.Submachine, Exit: g.Exit,
+			Position: g.Position, Choice: g.Choice, Defer: g.Defer, Join: g.Join,
+			Timeout: g.Timeout}
+		if state.Entries, ok = unpair(g.Entries); !ok {
+			return nil, errors.New(errCorruptEncoding)
+		}
+		if state.Flags, ok = unpair(g.Flags); !ok {
+			return nil, errors.New(errCorruptEncoding)
+		}
+		if len(g.Symbols) != len(g.Targets) || len(g.WeightSymbols) != len(g.Weights) ||
+			len(g.RouteSymbols) != len(g.Routes) || len(g.GuardSymbols) != len(g.Guards) ||
+			len(g.AssignSymbols) != len(g.Assign) {
+			return nil, errors.New(errCorruptEncoding)
+		}
+		for i, symbol := range g.Symbols {
+			if state.Transitions == nil {
+				state.Transitions = make(map[string]string, len(g.Symbols))
+			}
+			state.Transitions[symbol] = g.Targets[i]
+		}
+		for i, symbol := range g.WeightSymbols {
+			if state.Weights == nil {
+				state.Weights = make(map[string]float64, len(g.Weights))
+			}
+			state.Weights[symbol] = g.Weights[i]
+		}
+		for i, symbol := range g.RouteSymbols {
+			if state.Routes == nil {
+				state.Routes = make(map[string][]Point, len(g.Routes))
+			}
+			state.Routes[symbol] = g.Routes[i]
+		}
+		for i, symbol := range g.GuardSymbols {
+			if state.Guards == nil {
+				state.Guards = make(map[string]string, len(g.Guards))
+			}
+			state.Guards[symbol] = g.Guards[i]
+		}
+		for i, symbol := range g.AssignSymbols {
+			if state.Assign == nil {
+				state.Assign = make(map[string]string, len(g.Assign))
+			}
+			state.Assign[symbol] = g.Assign[i]
+		}
+		definition.States = append(definition.States, state)
+	}
+	return definition, nil
+}
+
+// gobSnapshot is the gob form of a snapshot, with the variables sorted by
+// name like the maps of gobDefinition.
+type gobSnapshot struct {
+	Active    []string
+	VarNames  []string
+	VarValues []any
+	Joined    [][]string
+	Entered   []time.Time
+	Deferred  [][]string
+	Seen      []string
+}
+
+func (gobCodec) EncodeSnapshot(w io.Writer, snapshot RunnerSnapshot) error {
+	encoded := gobSnapshot{Active: snapshot.Active, Joined: snapshot.Joined, Entered: snapshot.Entered,
+		Deferred: snapshot.Deferred, Seen: snapshot.Seen}
+	for _, name := range sortedKeys(snapshot.Vars) {
+		encoded.VarNames = append(encoded.VarNames, name)
+		encoded.VarValues = append(encoded.VarValues, snapshot.Vars[name])
+	}
+	return gob.NewEncoder(w).Encode(&encoded)
+}
+
+func (gobCodec) DecodeSnapshot(r io.Reader) (RunnerSnapshot, error) {
+	var decoded gobSnapshot
+	if err := gob.NewDecoder(r).Decode(&decoded); err != nil {
+		return RunnerSnapshot{}, err
+	}
+	if len(decoded.VarNames) != len(decoded.VarValues) {
+		return RunnerSnapshot{}, errors.New(errCorruptEncoding)
+	}
+	snapshot := RunnerSnapshot{Active: decoded.Active, Joined: decoded.Joined, Entered: decoded.Entered,
+		Deferred: decoded.Deferred, Seen: decoded.Seen}
+	for i, name := range decoded.VarNames {
+		if snapshot.Vars == nil {
+			snapshot.Vars = make(Vars, len(decoded.VarNames))
+		}
+		snapshot.Vars[name] = decoded.VarValues[i]
+	}
+	return snapshot, nil
+}
+
+// pairs returns the keys and values of the map sorted by key.
+func pairs(names map[string]string) [2][]string {
+	var p [2][]string
+	for _, key := range sortedKeys(names) {
+		p[0] = append(p[0], key)
+		p[1] = append(p[1], names[key])
+	}
+	return p
+}
+
+// unpair rebuilds a map from its pairs, nil if there are none.
+func unpair(p [2][]string) (map[string]string, bool) {
+	if len(p[0]) != len(p[1]) {
+		return nil, false
+	}
+	if len(p[0]) == 0 {
+		return nil, true
+	}
+	names := make(map[string]string, len(p[0]))
+	for i, key := range p[0] {
+		names[key] = p[1][i]
+	}
+	return names, true
+}
@@ -0,0 +1,94 @@
+package dfa
+
+const (
+	leftTag   = "l"
+	rightTag  = "r"
+	repeatTag = "*"
+)
+
+// Seq returns a new DFA accepting every word that is a word of a
+// followed by a word of b.
+func Seq(a, b *DFA) *DFA {
+	return concat("seq("+a.Name+","+b.Name+")", a, b)
+}
+
+// Alt returns a new DFA accepting every word that is accepted by a or b.
+func Alt(a, b *DFA) *DFA {
+	return product("alt("+a.Name+","+b.Name+")", a, b, func(p, q bool) bool { return p || q })
+}
+
+// Repeat returns a new DFA accepting every concatenation of zero or
+// more words of a (Kleene star).
+func Repeat(a *DFA) *DFA {
+	restart := tagKey(repeatTag, a.Start)
+	next := func(key, symbol string) []string {
+		_, state := untagKey(key)
+		to, ok := delta(a, state, symbol)
+		if !ok {
+			return nil
+		}
+		if isFinal(a, to) {
+			return []string{tagKey(leftTag, to), restart}
+		}
+		return []string{tagKey(leftTag, to)}
+	}
+	final := func(key string) bool {
+		tag, state := untagKey(key)
+		return tag == repeatTag || isFinal(a, state)
+	}
+	return determinize("repeat("+a.Name+")", a.stateNamer(), []string{restart}, alphabetOf(a), next, final, taggedLabel(a, a))
+}
+
+// Parallel returns a new DFA that runs a and b in lockstep on the same
+// input and accepts only the words that both of them accept. It is the
+// intersection of both (see Intersect), named "parallel(a,b)".
+func Parallel(a, b *DFA) *DFA {
+	m := Intersect(a, b)
+	m.Name = "parallel(" + a.Name + "," + b.Name + ")"
+	return m
+}
+
+// concat executes the construction behind Seq and Concat.
+func concat(name string, a, b *DFA) *DFA {
+	closure := func(keys []string) []string {
+		for _, key := range keys {
+			if tag, state := untagKey(key); tag == leftTag && isFinal(a, state) {
+				return append(keys, tagKey(rightTag, b.Start))
+			}
+		}
+		return keys
+	}
+	next := func(key, symbol string) []string {
+		tag, state := untagKey(key)
+		if tag == leftTag {
+			if to, ok := delta(a, state, symbol); ok {
+				return closure([]string{tagKey(leftTag, to)})
+			}
+			return nil
+		}
+		if to, ok := delta(b, state, symbol); ok {
+			return []string{tagKey(rightTag, to)}
+		}
+		return nil
+	}
+	final := func(key string) bool {
+		tag, state := untagKey(key)
+		return tag == rightTag && isFinal(b, state)
+	}
+	start := closure([]string{tagKey(leftTag, a.Start)})
+	return determinize(name, a.stateNamer(), start, alphabetOf(a, b), next, final, taggedLabel(a, b))
+}
+
+// taggedLabel renders tagged keys qualified by the name of their machine.
+func taggedLabel(a, b *DFA) func(string) string {
+	return func(key string) string {
+		tag, state := untagKey(key)
+		switch tag {
+		case rightTag:
+			return b.Name + "." + state
+		case repeatTag:
+			return a.Name + "." + state + "*"
+		}
+		return a.Name + "." + state
+	}
+}
@@ -0,0 +1,149 @@
+package dfa
+
+import "errors"
+
+// CompiledDFA is an immutable snapshot of a DFA that is safe to execute
+// from many goroutines. Hooks, guards and metrics are not part of it.
+type CompiledDFA struct {
+	name       string
+	start      int
+	index      map[string]int
+	states     []compiledState
+	known      map[string]bool
+	classifier Classifier
+	matcher    Matcher
+	policy     UnknownPolicy
+	sink       int
+}
+
+// compiledState is a state of a CompiledDFA, targets are state indexes
+// and -1 marks a transition into a state that does not exist.
+type compiledState struct {
+	name        string
+	final       bool
+	transitions map[string]int
+	symbols     []string
+}
+
+// Compile returns an immutable snapshot of the DFA. Later changes to the
+// DFA do not affect the snapshot. Concurrent use of a DFA is only safe as
+// long as it is not changed, the snapshot can be used while it is.
+func (m *DFA) Compile() *CompiledDFA {
+	names := sortedKeys(m.States)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	lookup := func(name string) int {
+		if i, ok := index[name]; ok {
+			return i
+		}
+		return -1
+	}
+	compiled := &CompiledDFA{
+		name:       m.Name,
+		start:      lookup(m.Start),
+		index:      index,
+		states:     make([]compiledState, len(names)),
+		known:      make(map[string]bool),
+		classifier: m.classifier,
+		matcher:    m.matcher,
+		policy:     m.unknownPolicy,
+		sink:       lookup(m.sink),
+	}
+	for i, name := range names {
+		state := m.States[name]
+		transitions := make(map[string]int, len(state.Transitions))
+		for symbol, to := range state.Transitions {
+			transitions[symbol] = lookup(to)
+			compiled.known[symbol] = true
+		}
+		compiled.states[i] = compiledState{
+			name:        name,
+			final:       state.Final,
+			transitions: transitions,
+			symbols:     sortedSymbols(state),
+		}
+	}
+	if m.Alphabet != nil {
+		compiled.known = make(map[string]bool, len(m.Alphabet))
+		for _, symbol := range m.Alphabet {
+			compiled.known[symbol] = true
+		}
+	}
+	return compiled
+}
+
+// Name returns the name of the compiled DFA.
+func (c *CompiledDFA) Name() string {
+	return c.name
+}
+
+// Step returns the state the symbol leads to from the given state,
+// resolving the symbol like DFA.Step does.
+func (c *CompiledDFA) Step(state, symbol string) (string, bool) {
+	i, ok := c.index[state]
+	if !ok {
+		return "", false
+	}
+	to, ok := c.next(i, symbol)
+	if !ok || to < 0 {
+		return "", false
+	}
+	return c.states[to].name, true
+}
+
+// Run runs the tokens with the semantics of DFA.Run.
+func (c *CompiledDFA) Run(tokens []string) ([]string, bool, error) {
+	var path []string
+	if len(c.states) == 0 {
+		return path, false, errors.New(errNoStates)
+	}
+	if c.start < 0 {
+		return path, false, errors.New(errNoStartState)
+	}
+	current := c.start
+	for _, token := range tokens {
+		if current < 0 {
+			return path, false, errors.New(errStateNotExistent)
+		}
+		path = append(path, c.states[current].name)
+		if c.states[current].final {
+			return path, true, nil
+		}
+		to, ok := c.next(current, token)
+		if !ok {
+			return path, false, nil
+		}
+		current = to
+	}
+	return path, true, nil
+}
+
+// next resolves a symbol in a state in the order used by DFA.resolve.
+func (c *CompiledDFA) next(state int, symbol string) (int, bool) {
+	if c.classifier != nil {
+		symbol = c.classifier(symbol)
+	}
+	s := &c.states[state]
+	if to, ok := s.transitions[symbol]; ok {
+		return to, true
+	}
+	if c.matcher != nil {
+		for _, transition := range s.symbols {
+			if c.matcher(transition, symbol) {
+				return s.transitions[transition], true
+			}
+		}
+	}
+	if to, ok := s.transitions[AnySymbol]; ok {
+		return to, true
+	}
+	if c.policy == RejectUnknown || c.known[symbol] {
+		return 0, false
+	}
+	if c.policy == IgnoreUnknown {
+		return state, true
+	}
+	return c.sink, true
+}
@@ -0,0 +1,35 @@
+package dfa
+
+// Completion is the symbol of completion transitions, which have no
+// trigger.
+const Completion = ""
+
+// AddCompletion adds a completion transition into the state. A Runner
+// takes it right away when it enters the state without consuming a token,
+// unless its guard denies it (see SetGuard and SetGuardExpr). Composite
+// states complete once their submachine reaches a final state if their
+// exit symbol is Completion. Only Runner takes completion transitions
+// automatically.
+func (s *State) AddCompletion(state *State) {
+	s.AddTransition(state, Completion)
+}
+
+// Completes tests if the state has a completion transition.
+func (s *State) Completes() bool {
+	_, ok := s.Transitions[Completion]
+	return ok
+}
+
+// SetCompletionEvent sets the implicit symbol a composite state of the
+// machine receives once its submachine reaches a final state, if the state
+// has no exit symbol (see State.SetSubmachine). Nested submachines bubble
+// their completion up the same way. It defaults to Completion, so the
+// completion transition of the composite state is taken.
+func (m *DFA) SetCompletionEvent(symbol string) {
+	m.completionEvent = symbol
+}
+
+// CompletionEvent returns the implicit symbol of finished submachines.
+func (m *DFA) CompletionEvent() string {
+	return m.completionEvent
+}
@@ -0,0 +1,48 @@
+package dfa
+
+// Compose returns the synchronized parallel composition of a and b.
+// On a symbol of syncSymbols both machines have to advance together,
+// every other symbol advances whichever machine is able to consume it.
+// A word is accepted when both machines end up in a final state.
+func Compose(a, b *DFA, syncSymbols []string) *DFA {
+	return compose("compose("+a.Name+","+b.Name+")", a, b, syncSymbols)
+}
+
+// Interleave returns a new DFA accepting every interleaving (shuffle) of
+// a word of a with a word of b.
+func Interleave(a, b *DFA) *DFA {
+	return compose("interleave("+a.Name+","+b.Name+")", a, b, nil)
+}
+
+// compose builds the product automaton behind Compose and Interleave.
+func compose(name string, a, b *DFA, syncSymbols []string) *DFA {
+	sync := make(map[string]bool)
+	for _, symbol := range syncSymbols {
+		sync[symbol] = true
+	}
+	next := func(key, symbol string) []string {
+		p, q := unpairKey(key)
+		toA, okA := delta(a, p, symbol)
+		toB, okB := delta(b, q, symbol)
+		if sync[symbol] {
+			if okA && okB {
+				return []string{pairKey(toA, toB)}
+			}
+			return nil
+		}
+		var targets []string
+		if okA {
+			targets = append(targets, pairKey(toA, q))
+		}
+		if okB {
+			targets = append(targets, pairKey(p, toB))
+		}
+		return targets
+	}
+	final := func(key string) bool {
+		p, q := unpairKey(key)
+		return isFinal(a, p) && isFinal(b, q)
+	}
+	start := []string{pairKey(a.Start, b.Start)}
+	return determinize(name, a.stateNamer(), start, alphabetOf(a, b), next, final, pairLabel)
+}
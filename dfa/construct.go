@@ -0,0 +1,178 @@
+package dfa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	keySeparator = "\x00"
+	setSeparator = "\x01"
+)
+
+// delta looks up the transition of a state for a symbol within a machine.
+func delta(m *DFA, state, symbol string) (string, bool) {
+	if !m.StateExists(state) {
+		return "", false
+	}
+	return m.States[state].Via(symbol)
+}
+
+// isFinal tests if the state exists within the machine and is final.
+func isFinal(m *DFA, state string) bool {
+	return m.StateExists(state) && m.States[state].Final
+}
+
+// accepts tests if the tokens drive the machine from its start state
+// into a final state.
+func accepts(m *DFA, tokens []string) bool {
+	current := m.Start
+	for _, token := range tokens {
+		next, ok := delta(m, current, token)
+		if !ok {
+			return false
+		}
+		current = next
+	}
+	return isFinal(m, current)
+}
+
+// alphabetOf returns the sorted distinct symbols used by the machines.
+func alphabetOf(machines ...*DFA) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, m := range machines {
+		for _, state := range m.States {
+			for symbol := range state.Transitions {
+				if !seen[symbol] {
+					seen[symbol] = true
+					symbols = append(symbols, symbol)
+				}
+			}
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// tagKey builds an internal key for a state of one of several machines.
+func tagKey(tag, state string) string {
+	return tag + keySeparator + state
+}
+
+// untagKey splits an internal key into its tag and state name.
+func untagKey(key string) (string, string) {
+	parts := strings.SplitN(key, keySeparator, 2)
+	if len(parts) < 2 {
+		return "", key
+	}
+	return parts[0], parts[1]
+}
+
+// pairKey builds an internal key for a pair of states.
+func pairKey(a, b string) string {
+	return a + keySeparator + b
+}
+
+// unpairKey splits an internal pair key into both state names.
+func unpairKey(key string) (string, string) {
+	parts := strings.SplitN(key, keySeparator, 2)
+	if len(parts) < 2 {
+		return key, ""
+	}
+	return parts[0], parts[1]
+}
+
+// pairLabel renders a pair key in a readable way.
+func pairLabel(key string) string {
+	a, b := unpairKey(key)
+	return "(" + a + "," + b + ")"
+}
+
+// subsetKey returns the sorted, deduplicated keys and the lookup key of the set.
+func subsetKey(keys []string) ([]string, string) {
+	set := make([]string, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			set = append(set, key)
+		}
+	}
+	sort.Strings(set)
+	return set, strings.Join(set, setSeparator)
+}
+
+// subsetLabel renders a set of internal keys as a readable state name.
+func subsetLabel(set []string, label func(string) string) string {
+	if len(set) == 1 {
+		return label(set[0])
+	}
+	labels := make([]string, len(set))
+	for i, key := range set {
+		labels[i] = label(key)
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// determinize executes the subset construction over an implicitly given
+// nondeterministic automaton. The start set and the results of next have
+// to be closed already. Only reachable, non-empty subsets become states.
+func determinize(name string, namer Namer, start []string, alphabet []string,
+	next func(key, symbol string) []string, final func(key string) bool,
+	label func(key string) string) *DFA {
+	m := NewDFA(name)
+	m.SetNamer(namer)
+	names := make(map[string]string)
+	used := make(map[string]bool)
+	var queue [][]string
+
+	visit := func(keys []string) string {
+		set, key := subsetKey(keys)
+		if n, ok := names[key]; ok {
+			return n
+		}
+		n := uniqueName(namer.Name(subsetLabel(set, label)), used)
+		names[key] = n
+		state := NewState(n)
+		for _, k := range set {
+			if final(k) {
+				state.SetFinal(true)
+				break
+			}
+		}
+		m.SetState(state)
+		queue = append(queue, set)
+		return n
+	}
+
+	m.SetStart(visit(start))
+	for len(queue) > 0 {
+		set := queue[0]
+		queue = queue[1:]
+		_, key := subsetKey(set)
+		from := m.States[names[key]]
+		for _, symbol := range alphabet {
+			var targets []string
+			for _, k := range set {
+				targets = append(targets, next(k, symbol)...)
+			}
+			if len(targets) == 0 {
+				continue
+			}
+			from.Transitions[symbol] = visit(targets)
+		}
+	}
+	return m
+}
+
+// uniqueName returns the name or, if already taken, the name with a suffix.
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 1; used[candidate]; i++ {
+		candidate = name + "#" + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,81 @@
+package dfa
+
+// CoverageSample returns accepted words that together take every
+// transition between live states (reachable from the start and able to
+// reach a final state) at least once. Every word is built around a
+// transition not covered yet: the shortest way from the start to its
+// source, the transition and the shortest way from its target to a final
+// state. At most limit words are returned, all if limit is 0. The result
+// is deterministic, ties are broken by the order of the symbols.
+func (m *DFA) CoverageSample(limit int) [][]string {
+	live := m.liveStates()
+	if len(live) == 0 {
+		return nil
+	}
+	type edge struct{ from, symbol string }
+	covered := make(map[edge]bool)
+	var words [][]string
+	add := func(word []string) {
+		current := m.Start
+		for _, symbol := range word {
+			covered[edge{current, symbol}] = true
+			current = m.States[current].Transitions[symbol]
+		}
+		words = append(words, word)
+	}
+	toFinal := func(name string) bool { return isFinal(m, name) }
+	for _, name := range sortedKeys(live) {
+		state := m.States[name]
+		for _, symbol := range sortedSymbols(state) {
+			if limit > 0 && len(words) >= limit {
+				return words
+			}
+			to := state.Transitions[symbol]
+			if !live[to] || covered[edge{name, symbol}] {
+				continue
+			}
+			prefix, _ := m.shortestWord(m.Start, func(s string) bool { return s == name }, live)
+			suffix, _ := m.shortestWord(to, toFinal, live)
+			word := append(append(prefix, symbol), suffix...)
+			add(word)
+		}
+	}
+	if len(words) == 0 && isFinal(m, m.Start) {
+		words = append(words, []string{})
+	}
+	return words
+}
+
+// shortestWord returns the shortest word leading from the state into a
+// state satisfying goal, only passing the allowed states. Ties are broken
+// by the order of the symbols.
+func (m *DFA) shortestWord(from string, goal func(string) bool, allowed map[string]bool) ([]string, bool) {
+	type step struct{ previous, symbol string }
+	visited := map[string]step{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if goal(name) {
+			var word []string
+			for name != from {
+				word = append([]string{visited[name].symbol}, word...)
+				name = visited[name].previous
+			}
+			return word, true
+		}
+		state := m.GetState(name)
+		if state == nil {
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			to := state.Transitions[symbol]
+			if _, seen := visited[to]; seen || !allowed[to] {
+				continue
+			}
+			visited[to] = step{name, symbol}
+			queue = append(queue, to)
+		}
+	}
+	return nil, false
+}
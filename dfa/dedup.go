@@ -0,0 +1,79 @@
+package dfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicate is returned if an event was already fed (see FeedEvent).
+var ErrDuplicate = errors.New("duplicate event")
+
+// dedup remembers the IDs of the recent events.
+type dedup struct {
+	capacity int
+	// ids holds the IDs oldest first, seen the same IDs as set
+	ids  []string
+	seen map[string]bool
+}
+
+// SetDedup makes the runner remember the IDs of the last capacity events
+// fed with FeedEvent, so events redelivered by at-least-once brokers do not
+// trigger transitions and actions twice. The IDs are part of the snapshot
+// (see Snapshot), a reset forgets them. 0 disables deduplication.
+func (r *Runner) SetDedup(capacity int) {
+	if capacity <= 0 {
+		r.dedup = nil
+		return
+	}
+	recent := r.dedup.recent()
+	r.dedup = &dedup{capacity: capacity}
+	r.dedup.reset(recent)
+}
+
+// FeedEvent feeds the token of the event with the ID like FeedContext. If
+// deduplication is enabled and the ID is among the recent events, the
+// token is not fed and the error wraps ErrDuplicate. IDs are remembered
+// once their token was fed without error, also if it was rejected.
+func (r *Runner) FeedEvent(ctx context.Context, id, token string) (string, bool, error) {
+	if r.dedup != nil && r.dedup.seen[id] {
+		return r.current, false, fmt.Errorf("%w '%s'", ErrDuplicate, id)
+	}
+	current, ok, err := r.FeedContext(ctx, token)
+	if err == nil {
+		r.dedup.add(id)
+	}
+	return current, ok, err
+}
+
+// add remembers the ID, forgetting the oldest beyond the capacity.
+func (d *dedup) add(id string) {
+	if d == nil || d.seen[id] {
+		return
+	}
+	d.ids = append(d.ids, id)
+	d.seen[id] = true
+	if len(d.ids) > d.capacity {
+		delete(d.seen, d.ids[0])
+		d.ids = d.ids[1:]
+	}
+}
+
+// recent returns a copy of the remembered IDs, oldest first.
+func (d *dedup) recent() []string {
+	if d == nil || len(d.ids) == 0 {
+		return nil
+	}
+	return append([]string{}, d.ids...)
+}
+
+// reset remembers the IDs instead, keeping the newest within the capacity.
+func (d *dedup) reset(ids []string) {
+	if d == nil {
+		return
+	}
+	d.ids, d.seen = nil, make(map[string]bool, d.capacity)
+	for _, id := range ids {
+		d.add(id)
+	}
+}
@@ -0,0 +1,55 @@
+package dfa
+
+import "context"
+
+// Defer lets the state defer the symbols: a Runner queues them instead of
+// rejecting them while it is in the state and re-delivers them after its
+// next transition. A transition of the state for a symbol takes precedence
+// over deferring it.
+func (s *State) Defer(symbols ...string) {
+	if s.deferred == nil {
+		s.deferred = make(map[string]bool, len(symbols))
+	}
+	for _, symbol := range symbols {
+		s.deferred[symbol] = true
+	}
+}
+
+// Deferred returns the symbols deferred by the state, sorted.
+func (s *State) Deferred() []string {
+	return sortedKeys(s.deferred)
+}
+
+// Deferred returns the tokens queued by the runner in the order they were
+// fed.
+func (r *Runner) Deferred() []string {
+	return append([]string{}, r.deferred...)
+}
+
+// deferToken queues the token if the current state defers it and has no
+// transition for it.
+func (r *Runner) deferToken(token string) bool {
+	state := r.machine.GetState(r.current)
+	if state == nil || !state.deferred[token] {
+		return false
+	}
+	if _, _, ok := r.machine.resolve(state, token); ok {
+		return false
+	}
+	r.deferred = append(r.deferred, token)
+	return true
+}
+
+// redeliver feeds the queued tokens again after a transition, tokens that
+// are deferred again stay queued.
+func (r *Runner) redeliver(ctx context.Context) error {
+	queued := r.deferred
+	r.deferred = nil
+	for i, token := range queued {
+		if _, _, err := r.feed(ctx, token); err != nil {
+			r.deferred = append(r.deferred, queued[i+1:]...)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package dfa
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errNoTransition = "no transition for symbol"
+
+// Derive returns the DFA for the residual language after consuming the
+// given prefix (Brzozowski derivative). The resulting machine starts at the
+// state the prefix leads to and only contains the states reachable from it.
+func (m *DFA) Derive(symbolSequence []string) (*DFA, error) {
+	if !m.StateExists(m.Start) {
+		return nil, errors.New(errStateNotExistent)
+	}
+	current := m.Start
+	for _, symbol := range symbolSequence {
+		next, ok := delta(m, current, symbol)
+		if !ok {
+			return nil, fmt.Errorf("%s '%s' at state '%s'", errNoTransition, symbol, current)
+		}
+		current = next
+	}
+	return m.copyReachable(current), nil
+}
+
+// copyReachable returns a new DFA starting at the given state holding copies
+// of all states reachable from it.
+func (m *DFA) copyReachable(from string) *DFA {
+	copied := NewDFA(m.Name)
+	copied.SetNamer(m.namer)
+	copied.SetStart(from)
+	queue := []string{from}
+	visited := map[string]bool{from: true}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		state := m.GetState(name)
+		if state == nil {
+			continue
+		}
+		copied.SetState(state.copy())
+		for _, to := range state.Transitions {
+			if !visited[to] {
+				visited[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	return copied
+}
@@ -1,37 +1,95 @@
 package dfa
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
-	"log"
+	"expvar"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
 	errStateNotExistent = "state not existent"
+	errNoStates         = "not able to run DFA, no states"
+	errNoStartState     = "not able to run DFA, no start state"
 	directionDelimiter  = "->"
 )
 
-// Edge represents a connection from a state to a state
+// Edge represents a connection from a state to a state for a symbol
 type Edge struct {
-	From string
-	To   string
+	From   string
+	To     string
+	Symbol string
 }
 
 // DFA holds everything that is needed in order to execute the automaton.
 type DFA struct {
 	Name string
-	// States holds the state name as well as the state structure
+	// States holds the state name as well as the state structure.
+	//
+	// Deprecated: use GetState, GetStates or AllStates and SetState, changing
+	// the map directly bypasses the indexes.
 	States map[string]*State
 	// StateLookup holds key: symbol->symbol and all the states that
 	// are in between of this symbol->state->symbol constellation.
+	//
+	// Deprecated: use InspectStates.
 	StateLookup map[string][]string
 	// EdgeLookup holds key: symbol and as value all state pairs that
 	// are connected by this symbol.
+	//
+	// Deprecated: use EdgesFor or InspectSymbols.
 	EdgeLookup map[string][]*Edge
 	Indexed    bool
 	Start      string
+	// mu guards the indexes
+	mu sync.RWMutex
+	// contributions holds the index entries by the state that caused them,
+	// incoming holds the states with transitions into a state (by index)
+	contributions map[string][]indexEntry
+	incoming      map[string]map[string]bool
+	// Alphabet holds the declared alphabet, nil if it is not declared
+	Alphabet []string
+	// classifier maps input symbols to symbol classes before the lookup
+	classifier Classifier
+	// matcher is consulted if no transition matches a symbol exactly
+	matcher Matcher
+	// unknownPolicy and sink determine how symbols outside the alphabet are handled
+	unknownPolicy UnknownPolicy
+	sink          string
+	// onEnter and onExit hold the handlers by state
+	onEnter map[string][]Action
+	onExit  map[string][]Action
+	// metrics, stateMetrics and occupancy are set once the DFA is published
+	// via expvar, guarded by mu
+	metrics      *expvar.Map
+	stateMetrics *expvar.Map
+	occupancy    *expvar.Map
+	// tracer observes steps, rejections and acceptances
+	tracer Tracer
+	// namer names the states created by constructions
+	namer Namer
+	// entryPoints holds the states by entry point name, exitPoints the exit
+	// point names by state
+	entryPoints map[string]string
+	exitPoints  map[string]string
+	// namingPolicy is enforced by AddState and AddTransition and reported by
+	// Validate
+	namingPolicy *NamingPolicy
+	// completionEvent is received by composite states without exit symbol
+	completionEvent string
+	// reachability is maintained while it is tracked
+	reachability *Reachability
+	// workers is the number of goroutines used by indexing and analyses
+	workers int
+	// progress is called while heavy operations proceed
+	progress func(done, total int64)
+	// profile holds the options applied by SetProfile
+	profile *Profile
 }
 
 // NewDFA creates a new DFA
@@ -45,6 +103,9 @@ func NewDFA(name string) *DFA {
 // SetStart sets the starting point of the DFA.
 func (m *DFA) SetStart(state string) {
 	m.Start = state
+	if m.reachability != nil {
+		m.reachability.recompute()
+	}
 }
 
 // GetStart returns the starting point of the DFA.
@@ -52,13 +113,40 @@ func (m *DFA) GetStart() string {
 	return m.Start
 }
 
-// SetSetate sets one state
+// SetState sets one state, replacing a state of the same name
 func (m *DFA) SetState(state *State) {
+	m.mu.Lock()
 	if m.States == nil {
 		m.States = make(map[string]*State)
 	}
 	m.States[state.Name] = state
-	m.Indexed = false
+	state.owner = m
+	if m.Indexed {
+		m.reindex(state.Name)
+	}
+	m.mu.Unlock()
+	if m.reachability != nil {
+		m.reachability.update(state.Name)
+	}
+}
+
+// RemoveState removes a state, transitions into it are kept.
+func (m *DFA) RemoveState(name string) {
+	m.mu.Lock()
+	state, ok := m.States[name]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.States, name)
+	state.owner = nil
+	if m.Indexed {
+		m.reindex(name)
+	}
+	m.mu.Unlock()
+	if m.reachability != nil {
+		m.reachability.update(name)
+	}
 }
 
 // SetStates is able to set multiple states at once
@@ -85,18 +173,33 @@ func (m *DFA) StateExists(name string) bool {
 }
 
 // Step executes one step in the DFA and determines if this step
-// is possible within this automaton.
+// is possible within this automaton. Guards are evaluated with an empty
+// context, an error wrapping ErrForbidden is returned if one denies the
+// transition (see StepContext), a *HookError if a hook failed it.
 func (m *DFA) Step(state, symbol string) (string, bool, error) {
 	if m.States[state] == nil {
 		return "", false, errors.New(errStateNotExistent)
 	}
-	if next, ok := m.States[state].Via(symbol); ok {
+	if transition, to, ok := m.resolve(m.States[state], symbol); ok {
+		if err := m.guard(context.Background(), m.States[state], transition, symbol); err != nil {
+			return "", false, err
+		}
+		next, err := m.take(m.States[state], transition, symbol, to, nil)
+		if err != nil {
+			return "", false, err
+		}
 		return next, true, nil
 	}
+	m.reject(state, symbol)
 	return "", false, nil
 }
 
 func (m *DFA) buildKey(from, to string) string {
+	return indexKey(from, to)
+}
+
+// indexKey builds the StateLookup key of two symbols.
+func indexKey(from, to string) string {
 	data := []byte(strings.Join([]string{from, to}, directionDelimiter))
 	hash := md5.Sum(data)
 	return hex.EncodeToString(hash[:])
@@ -104,31 +207,53 @@ func (m *DFA) buildKey(from, to string) string {
 
 // Index indexes all symbol to symbol transitions with the states
 // that are in between. And also indexes a symbol with all the
-// state pairs where it is in between.
+// state pairs where it is in between. Once indexed, the indexes are
+// updated incrementally when states are set or transitions are added.
+// The states are indexed by the workers of the DFA (see SetWorkers).
 func (m *DFA) Index() {
-	m.StateLookup = make(map[string][]string)
-	m.EdgeLookup = make(map[string][]*Edge)
-	for _, state := range m.States {
-		for symbol1, transition := range state.Transitions {
-			// StateLookup
-			for symbol2 := range m.States[transition].Transitions {
-				hash := m.buildKey(symbol1, symbol2)
-				if _, ok := m.StateLookup[hash]; !ok {
-					m.StateLookup[hash] = make([]string, 0)
-				}
-				m.StateLookup[hash] = append(m.StateLookup[hash], transition)
-			}
-			// SymbolLookup
-			if m.EdgeLookup[symbol1] == nil {
-				m.EdgeLookup[symbol1] = make([]*Edge, 0)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.index(nil)
+}
+
+// IndexContext indexes the DFA like Index, but aborts with *Canceled once
+// the context is done, keeping the previous indexes.
+func (m *DFA) IndexContext(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.index(ctx)
+}
+
+// index rebuilds all indexes, the caller has to hold the lock. The
+// context is only checked if it is not nil.
+func (m *DFA) index(ctx context.Context) error {
+	names := make([]string, 0, len(m.States))
+	for name := range m.States {
+		names = append(names, name)
+	}
+	entries := make([][]indexEntry, len(names))
+	var done atomic.Int64
+	parallel(m.workers, len(names), minChunk, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			if (i-lo)%checkEvery == 0 && ctx != nil && ctx.Err() != nil {
+				return
 			}
-			m.EdgeLookup[symbol1] = append(m.EdgeLookup[symbol1], &Edge{
-				From: state.Name,
-				To:   transition,
-			})
+			entries[i] = m.contributionsOf(names[i])
+			done.Add(1)
 		}
+	})
+	if done.Load() < int64(len(names)) {
+		return canceled(ctx, "index", done.Load(), int64(len(names)))
+	}
+	m.StateLookup = make(map[string][]string)
+	m.EdgeLookup = make(map[string][]*Edge)
+	m.contributions = make(map[string][]indexEntry)
+	m.incoming = make(map[string]map[string]bool)
+	for i, name := range names {
+		m.applyContributions(name, entries[i])
 	}
 	m.Indexed = true
+	return nil
 }
 
 // InspectStates returns (if indexed) all states that have a connection
@@ -136,10 +261,9 @@ func (m *DFA) Index() {
 // is in between these two symbols.
 func (m *DFA) InspectStates(from, to string) []string {
 	m.ensureIndexed()
-	if states, ok := m.StateLookup[m.buildKey(from, to)]; ok {
-		return states
-	}
-	return []string{}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.StateLookup[m.buildKey(from, to)]...)
 }
 
 // InspectSymbols returns (if indexed) all states that have a connection
@@ -147,59 +271,112 @@ func (m *DFA) InspectStates(from, to string) []string {
 // is in between these two symbols.
 func (m *DFA) InspectSymbols(symbol string) []*Edge {
 	m.ensureIndexed()
-	if states, ok := m.EdgeLookup[symbol]; ok {
-		return states
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if edges, ok := m.EdgeLookup[symbol]; ok {
+		return append([]*Edge{}, edges...)
 	}
 	return nil
 }
 
-// GetSymbols returns distinct symbols used in this DFA
+// GetStates returns the states of the DFA ordered by name.
+func (m *DFA) GetStates() []*State {
+	states := make([]*State, 0, len(m.States))
+	for state := range m.AllStates() {
+		states = append(states, state)
+	}
+	return states
+}
+
+// EdgesFor returns copies of the edges connected by the symbol, so callers
+// cannot change the index.
+func (m *DFA) EdgesFor(symbol string) []Edge {
+	m.ensureIndexed()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var edges []Edge
+	for _, edge := range m.EdgeLookup[symbol] {
+		edges = append(edges, *edge)
+	}
+	return edges
+}
+
+// GetSymbols returns distinct symbols used in this DFA in sorted order
 func (m *DFA) GetSymbols() []string {
-	var symbols []string
 	m.ensureIndexed()
-	for s := range m.EdgeLookup {
-		if !contains(symbols, s) {
-			symbols = append(symbols, s)
-		}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.EdgeLookup) == 0 {
+		return nil
 	}
-	return symbols
+	return sortedKeys(m.EdgeLookup)
 }
 
 // ensureIndexed ensures that the DFA was indexed before
 func (m *DFA) ensureIndexed() {
-	if !m.Indexed {
-		m.Index()
-		m.Indexed = true
+	m.mu.RLock()
+	indexed := m.Indexed
+	m.mu.RUnlock()
+	if !indexed {
+		m.mu.Lock()
+		if !m.Indexed {
+			m.index(nil)
+		}
+		m.mu.Unlock()
 	}
 }
 
 // Run runs the DFA from the starting point with the given events
-// and returns the states that the events have taken
-func (m *DFA) Run(tokens []string) ([]string, bool) {
+// and returns the states that the events have taken. An error is
+// returned if the DFA has no states, the run hits a missing state, a guard
+// denies a transition or a hook fails it (see Step) or the run would exceed the maximum number
+// of transitions of the profile (see ErrMaxSteps).
+func (m *DFA) Run(tokens []string) ([]string, bool, error) {
 	var path []string
 	if m.States == nil {
-		log.Fatal("not able to run() DFA, no states")
+		return path, false, errors.New(errNoStates)
 	}
 	if _, ok := m.States[m.Start]; !ok {
-		log.Fatal("not able to run() DFA, no start state")
+		return path, false, errors.New(errNoStartState)
 	}
 	current := m.Start
-	for _, token := range tokens {
+	m.observeEnter(current)
+	limit := m.maxSteps()
+	for i, token := range tokens {
 		path = append(path, current)
 		if m.States[current] == nil {
-			log.Fatalf("state not existent")
+			return path, false, errors.New(errStateNotExistent)
 		}
 
 		if m.States[current].Final {
-			return path, true
+			return m.accept(path), true, nil
+		}
+		if limit > 0 && i >= limit {
+			return path, false, fmt.Errorf("%w: %d", ErrMaxSteps, limit)
 		}
-		state, ok := m.States[current].Via(token)
+		transition, to, ok := m.resolve(m.States[current], token)
 		if !ok {
-			return path, false
+			m.reject(current, token)
+			return path, false, nil
 		}
-		current = state
+		if err := m.guard(context.Background(), m.States[current], transition, token); err != nil {
+			return path, false, err
+		}
+		next, err := m.take(m.States[current], transition, token, to, nil)
+		if err != nil {
+			return path, false, err
+		}
+		current = next
+	}
+	return m.accept(path), true, nil
+}
+
+// accept reports an accepted path to the tracer and returns it.
+func (m *DFA) accept(path []string) []string {
+	if m.tracer != nil {
+		m.tracer.OnAccept(path)
 	}
-	return path, true
+	return path
 }
 
 // Classic contains function
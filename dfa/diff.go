@@ -0,0 +1,125 @@
+package dfa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff holds the structural differences between two versions of a machine.
+type Diff struct {
+	AddedStates   []string
+	RemovedStates []string
+	// AddedEdges and RemovedEdges hold transitions as Hit{From, Symbol, To}
+	AddedEdges   []Hit
+	RemovedEdges []Hit
+	// FinalChanged holds the states of both versions whose Final flag differs
+	FinalChanged []string
+}
+
+// DiffMachines compares two versions of a machine by state names, Final
+// flags and transitions, the results are sorted.
+func DiffMachines(old, updated *DFA) *Diff {
+	diff := &Diff{}
+	for _, name := range sortedKeys(updated.States) {
+		previous := old.GetState(name)
+		switch {
+		case previous == nil:
+			diff.AddedStates = append(diff.AddedStates, name)
+		case previous.Final != updated.GetState(name).Final:
+			diff.FinalChanged = append(diff.FinalChanged, name)
+		}
+	}
+	for _, name := range sortedKeys(old.States) {
+		if !updated.StateExists(name) {
+			diff.RemovedStates = append(diff.RemovedStates, name)
+		}
+	}
+	diff.AddedEdges = missingEdges(updated, old)
+	diff.RemovedEdges = missingEdges(old, updated)
+	return diff
+}
+
+// Empty tests if the versions do not differ.
+func (d *Diff) Empty() bool {
+	return len(d.AddedStates)+len(d.RemovedStates)+len(d.AddedEdges)+len(d.RemovedEdges)+
+		len(d.FinalChanged) == 0
+}
+
+// missingEdges returns the transitions of m that other does not have.
+func missingEdges(m, other *DFA) []Hit {
+	var edges []Hit
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		for _, symbol := range sortedSymbols(state) {
+			to := state.Transitions[symbol]
+			if existing := other.GetState(name); existing == nil || existing.Transitions[symbol] != to {
+				edges = append(edges, Hit{From: name, Symbol: symbol, To: to})
+			}
+		}
+	}
+	return edges
+}
+
+// ToDOTDiff exports both versions of a machine as one DOT graph in which
+// added states and transitions are drawn green, removed ones red and states
+// whose Final flag changed orange.
+func ToDOTDiff(old, updated *DFA) string {
+	diff := DiffMachines(old, updated)
+	color := func(added, removed bool) string {
+		switch {
+		case added:
+			return ", color=green, fontcolor=green"
+		case removed:
+			return ", color=red, fontcolor=red"
+		}
+		return ""
+	}
+	changed := func(name string) string {
+		if contains(diff.FinalChanged, name) {
+			return ", color=orange, fontcolor=orange"
+		}
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(updated.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  __start [shape=point];\n")
+	states := make(map[string]*State)
+	for name, state := range old.States {
+		states[name] = state
+	}
+	for name, state := range updated.States {
+		states[name] = state
+	}
+	for _, name := range sortedKeys(states) {
+		shape := "circle"
+		if states[name].Final {
+			shape = "doublecircle"
+		}
+		fmt.Fprintf(&b, "  %s [shape=%s%s%s];\n", dotQuote(name), shape,
+			color(contains(diff.AddedStates, name), contains(diff.RemovedStates, name)), changed(name))
+	}
+	if updated.Start != "" {
+		fmt.Fprintf(&b, "  __start -> %s;\n", dotQuote(updated.Start))
+	}
+	writeEdge := func(edge Hit, style string) {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n",
+			dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Symbol), style)
+	}
+	added := make(map[Hit]bool)
+	for _, edge := range diff.AddedEdges {
+		added[edge] = true
+	}
+	for _, name := range sortedKeys(updated.States) {
+		state := updated.States[name]
+		for _, symbol := range sortedSymbols(state) {
+			edge := Hit{From: name, Symbol: symbol, To: state.Transitions[symbol]}
+			writeEdge(edge, color(added[edge], false))
+		}
+	}
+	for _, edge := range diff.RemovedEdges {
+		writeEdge(edge, color(false, true)+", style=dashed")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
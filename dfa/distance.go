@@ -0,0 +1,43 @@
+package dfa
+
+// deadKey marks the implicit dead state of a partial machine.
+const deadKey = "\x02dead"
+
+// LanguageDistance returns the fraction of words up to length maxLen (over
+// the combined alphabet of both machines) on which a and b disagree about
+// acceptance. 0 means no difference was found, 1 means they disagree on
+// every word.
+func LanguageDistance(a, b *DFA, maxLen int) float64 {
+	alphabet := alphabetOf(a, b)
+	step := func(m *DFA, state, symbol string) string {
+		if to, ok := delta(m, state, symbol); ok {
+			return to
+		}
+		return deadKey
+	}
+	disagree := func(key string) bool {
+		p, q := unpairKey(key)
+		return isFinal(a, p) != isFinal(b, q)
+	}
+	current := map[string]float64{pairKey(a.Start, b.Start): 1}
+	var words, differing float64
+	for length := 0; length <= maxLen; length++ {
+		next := make(map[string]float64)
+		for _, key := range sortedKeys(current) {
+			count := current[key]
+			words += count
+			if disagree(key) {
+				differing += count
+			}
+			if length == maxLen {
+				continue
+			}
+			p, q := unpairKey(key)
+			for _, symbol := range alphabet {
+				next[pairKey(step(a, p, symbol), step(b, q, symbol))] += count
+			}
+		}
+		current = next
+	}
+	return differing / words
+}
@@ -0,0 +1,151 @@
+package dfa
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// exampleWords is the number of example words listed by Markdown and HTML.
+const exampleWords = 5
+
+// document holds the content of a documentation page of a machine.
+type document struct {
+	name    string
+	diagram string
+	states  []documentRow
+	// alphabet, finals and words are the listed items
+	alphabet []string
+	finals   []string
+	words    []string
+}
+
+// documentRow is a row of the state table.
+type documentRow struct {
+	name         string
+	start, final bool
+	transitions  []Hit
+}
+
+// document collects the content of the documentation page.
+func (m *DFA) document() document {
+	doc := document{name: m.Name, diagram: m.ToDOTCollapsed(), alphabet: m.GetAlphabet()}
+	for _, state := range m.GetStates() {
+		if state.Final {
+			doc.finals = append(doc.finals, state.Name)
+		}
+		row := documentRow{name: state.Name, start: state.Name == m.Start, final: state.Final}
+		for _, symbol := range sortedSymbols(state) {
+			row.transitions = append(row.transitions,
+				Hit{From: state.Name, Symbol: symbol, To: state.Transitions[symbol]})
+		}
+		doc.states = append(doc.states, row)
+	}
+	for _, word := range m.CoverageSample(exampleWords) {
+		doc.words = append(doc.words, strings.Join(word, " "))
+	}
+	return doc
+}
+
+// Markdown generates a documentation page of the machine: its diagram in
+// DOT, a table of the states, the alphabet, the final states and example
+// words that are accepted.
+func (m *DFA) Markdown() string {
+	doc := m.document()
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", doc.name)
+	b.WriteString("## Diagram\n\n```dot\n")
+	b.WriteString(doc.diagram)
+	b.WriteString("```\n\n## States\n\n")
+	b.WriteString("| State | Start | Final | Transitions |\n")
+	b.WriteString("|-------|-------|-------|-------------|\n")
+	for _, row := range doc.states {
+		var transitions []string
+		for _, transition := range row.transitions {
+			transitions = append(transitions, fmt.Sprintf("`%s` → %s", transition.Symbol, transition.To))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownEscape(row.name), yesNo(row.start),
+			yesNo(row.final), markdownEscape(strings.Join(transitions, ", ")))
+	}
+	b.WriteString("\n## Alphabet\n\n")
+	writeList(&b, doc.alphabet)
+	b.WriteString("\n## Final states\n\n")
+	writeList(&b, doc.finals)
+	b.WriteString("\n## Accepted words\n\n")
+	var words []string
+	for _, word := range doc.words {
+		words = append(words, "`"+word+"`")
+	}
+	writeList(&b, words)
+	return b.String()
+}
+
+// HTML generates the documentation page of Markdown as a standalone HTML
+// document, the diagram is included as DOT source.
+func (m *DFA) HTML() string {
+	doc := m.document()
+	var b strings.Builder
+	name := html.EscapeString(doc.name)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n", name)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<h2>Diagram</h2>\n<pre>%s</pre>\n<h2>States</h2>\n", name,
+		html.EscapeString(doc.diagram))
+	b.WriteString("<table>\n<tr><th>State</th><th>Start</th><th>Final</th><th>Transitions</th></tr>\n")
+	for _, row := range doc.states {
+		var transitions []string
+		for _, transition := range row.transitions {
+			transitions = append(transitions, fmt.Sprintf("<code>%s</code> → %s",
+				html.EscapeString(transition.Symbol), html.EscapeString(transition.To)))
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n", html.EscapeString(row.name),
+			yesNo(row.start), yesNo(row.final), strings.Join(transitions, ", "))
+	}
+	b.WriteString("</table>\n<h2>Alphabet</h2>\n")
+	writeHTMLList(&b, doc.alphabet, false)
+	b.WriteString("<h2>Final states</h2>\n")
+	writeHTMLList(&b, doc.finals, false)
+	b.WriteString("<h2>Accepted words</h2>\n")
+	writeHTMLList(&b, doc.words, true)
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// writeList writes the items as a Markdown list, "none" if there are none.
+func writeList(b *strings.Builder, items []string) {
+	if len(items) == 0 {
+		b.WriteString("none\n")
+		return
+	}
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// writeHTMLList writes the escaped items as an HTML list, as code if
+// requested, "none" if there are none.
+func writeHTMLList(b *strings.Builder, items []string, code bool) {
+	if len(items) == 0 {
+		b.WriteString("<p>none</p>\n")
+		return
+	}
+	b.WriteString("<ul>\n")
+	for _, item := range items {
+		item = html.EscapeString(item)
+		if code {
+			item = "<code>" + item + "</code>"
+		}
+		fmt.Fprintf(b, "<li>%s</li>\n", item)
+	}
+	b.WriteString("</ul>\n")
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return ""
+}
+
+// markdownEscape escapes the characters that break Markdown table cells.
+func markdownEscape(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
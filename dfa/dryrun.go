@@ -0,0 +1,17 @@
+package dfa
+
+// SetDryRun switches the runner into (or out of) the dry-run mode. In this
+// mode transitions still advance the runner, but the enter and exit hooks
+// and the actions of the transitions are skipped and no metrics are
+// counted, the runner does not count in the occupancy of its state either
+// (see DFA.Publish). The context of every skipped transition is passed to record
+// instead, record may be nil. Active submachines keep their mode until
+// they are entered again.
+func (r *Runner) SetDryRun(enabled bool, record func(ctx Context)) {
+	if enabled {
+		r.release()
+	}
+	r.dryRun = enabled
+	r.record = record
+	r.occupy(r.current)
+}
@@ -0,0 +1,65 @@
+package dfa
+
+import "fmt"
+
+// EdgesBetween returns the sorted symbols of all transitions from one
+// state into another.
+func (m *DFA) EdgesBetween(from, to string) []string {
+	state := m.GetState(from)
+	if state == nil {
+		return nil
+	}
+	var symbols []string
+	for _, symbol := range sortedSymbols(state) {
+		if state.Transitions[symbol] == to {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// StatesSorted returns the names of all states in sorted order.
+func (m *DFA) StatesSorted() []string {
+	return sortedKeys(m.States)
+}
+
+// SymbolsSorted returns the symbols of the transitions of the state in
+// sorted order, nil if the state does not exist.
+func (m *DFA) SymbolsSorted(state string) []string {
+	s := m.GetState(state)
+	if s == nil {
+		return nil
+	}
+	return sortedSymbols(s)
+}
+
+// AddTransition adds the transition for the symbol from one state to
+// another like State.AddTransition, keeping the indexes up to date. An
+// error is returned if the source state does not exist or the symbol or the
+// target name violate the naming policy, the target may be set later.
+func (m *DFA) AddTransition(from, symbol, to string) error {
+	state := m.GetState(from)
+	if state == nil {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, from)
+	}
+	if err := m.namingPolicy.CheckSymbol(symbol); err != nil {
+		return fmt.Errorf("state '%s': %w", from, err)
+	}
+	if err := m.namingPolicy.CheckState(to); err != nil {
+		return err
+	}
+	state.AddTransition(NewState(to), symbol)
+	return nil
+}
+
+// RemoveTransition removes the transition for the symbol from the state
+// like State.RemoveTransition. An error is returned if the state does not
+// exist.
+func (m *DFA) RemoveTransition(from, symbol string) error {
+	state := m.GetState(from)
+	if state == nil {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, from)
+	}
+	state.RemoveTransition(symbol)
+	return nil
+}
@@ -0,0 +1,67 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/breskos/gopher-state/expr"
+)
+
+const errGuardDenied = "guard denied"
+
+// SetGuardExpr installs a guard like SetGuard that evaluates the expression
+// (see package expr) with the variables carried by the context, e.g.
+// "attempts < 3 && amount > 100". The transition is denied if the
+// expression is false or fails.
+func (s *State) SetGuardExpr(symbol, source string) error {
+	e, err := expr.Parse(source)
+	if err != nil {
+		return err
+	}
+	s.SetGuard(symbol, func(ctx context.Context, _ string) error {
+		ok, err := e.Bool(VarsFrom(ctx))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s '%s'", errGuardDenied, source)
+		}
+		return nil
+	})
+	if s.guardExprs == nil {
+		s.guardExprs = make(map[string]string)
+	}
+	s.guardExprs[symbol] = source
+	return nil
+}
+
+// SetAssignment installs assignments (see package expr) that update the
+// variables of a Runner whenever the transition of the symbol is taken,
+// e.g. "attempts += 1; last = amount". They are applied before the action
+// of the transition; if they fail, the transition is not taken.
+func (s *State) SetAssignment(symbol, source string) error {
+	assignment, err := expr.ParseAssignment(source)
+	if err != nil {
+		return err
+	}
+	if s.assignments == nil {
+		s.assignments = make(map[string]*expr.Assignment)
+	}
+	s.assignments[symbol] = assignment
+	return nil
+}
+
+// GuardExpr returns the source of the guard expression of the transition.
+func (s *State) GuardExpr(symbol string) (string, bool) {
+	source, ok := s.guardExprs[symbol]
+	return source, ok
+}
+
+// Assignment returns the source of the assignments of the transition.
+func (s *State) Assignment(symbol string) (string, bool) {
+	assignment, ok := s.assignments[symbol]
+	if !ok {
+		return "", false
+	}
+	return assignment.String(), true
+}
@@ -0,0 +1,79 @@
+package dfa
+
+import "expvar"
+
+const expvarPrefix = "gopher-state."
+
+// Publish exposes the counters of this machine via expvar under
+// "gopher-state.<name>". Once published, Step and Run count how often
+// every state was entered ("states") and the total number of transitions
+// taken ("transitions"), and "occupancy" holds the number of runners in
+// every state: runners count from their creation or their first transition
+// after publishing until they are closed (see Runner.Close), dry-run runners
+// do not count. Publishing the same name twice shares the counters.
+func (m *DFA) Publish() {
+	m.publish(expvarPrefix + m.Name)
+}
+
+// publish exposes the counters of this machine via expvar under the name.
+func (m *DFA) publish(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := expvar.Get(name).(*expvar.Map); ok {
+		m.metrics = existing
+	} else {
+		m.metrics = expvar.NewMap(name)
+	}
+	m.stateMetrics = m.metricsMap("states")
+	m.occupancy = m.metricsMap("occupancy")
+}
+
+// metricsMap returns the map of the published counters under the key,
+// creating it if it does not exist. The caller has to hold the lock.
+func (m *DFA) metricsMap(key string) *expvar.Map {
+	if existing, ok := m.metrics.Get(key).(*expvar.Map); ok {
+		return existing
+	}
+	created := new(expvar.Map).Init()
+	m.metrics.Set(key, created)
+	return created
+}
+
+// published returns the counters of the machine, nil if it is not published.
+func (m *DFA) published() (metrics, states, occupancy *expvar.Map) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.metrics, m.stateMetrics, m.occupancy
+}
+
+// observeEnter counts an entry into a state if the machine is published.
+func (m *DFA) observeEnter(state string) {
+	if _, states, _ := m.published(); states != nil {
+		states.Add(state, 1)
+	}
+}
+
+// observeTransition counts a transition into a state if the machine is published.
+func (m *DFA) observeTransition(to string) {
+	if metrics, states, _ := m.published(); metrics != nil {
+		metrics.Add("transitions", 1)
+		states.Add(to, 1)
+	}
+}
+
+// occupy moves an instance from one state to another in the occupancy if
+// the machine is published and reports whether it is, empty names are
+// skipped.
+func (m *DFA) occupy(from, to string) bool {
+	_, _, occupancy := m.published()
+	if occupancy == nil {
+		return false
+	}
+	if from != "" {
+		occupancy.Add(from, -1)
+	}
+	if to != "" {
+		occupancy.Add(to, 1)
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package dfa
+
+import "context"
+
+// FlagProvider resolves feature flags, e.g. per tenant carried by the
+// context.
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// FlagFunc adapts a function to a FlagProvider.
+type FlagFunc func(ctx context.Context, flag string) bool
+
+// Enabled calls the function.
+func (f FlagFunc) Enabled(ctx context.Context, flag string) bool {
+	return f(ctx, flag)
+}
+
+// SetFlag gates the transition of the symbol behind the feature flag. A
+// Runner only takes the transition while its FlagProvider enables the
+// flag, otherwise the transition is treated as missing; without a provider
+// all flags are disabled. Step and Run ignore flags.
+func (s *State) SetFlag(symbol, flag string) {
+	if s.flags == nil {
+		s.flags = make(map[string]string)
+	}
+	s.flags[symbol] = flag
+}
+
+// Flag returns the feature flag of the transition.
+func (s *State) Flag(symbol string) (string, bool) {
+	flag, ok := s.flags[symbol]
+	return flag, ok
+}
+
+// SetFlagProvider sets the provider resolving the feature flags of
+// transitions when tokens are fed, it is shared with submachines and the
+// shadow. The provider gets the context the token is fed with, which
+// carries the variables of the runner. Passing nil disables all flags.
+func (r *Runner) SetFlagProvider(provider FlagProvider) {
+	r.flags = provider
+	if r.inner != nil {
+		r.inner.SetFlagProvider(provider)
+	}
+	if r.shadow != nil {
+		r.shadow.SetFlagProvider(provider)
+	}
+}
+
+// flagged tests if the transition is gated behind a disabled flag.
+func (r *Runner) flagged(ctx context.Context, state *State, transition string) bool {
+	flag, ok := state.flags[transition]
+	if !ok {
+		return false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.flags == nil || !r.flags.Enabled(ctx, flag)
+}
@@ -0,0 +1,35 @@
+package dfa
+
+import (
+	"context"
+	"testing"
+)
+
+type tenant struct{}
+
+func TestFlaggedChoiceBranchPerTenant(t *testing.T) {
+	m := NewDFA("tenants")
+	start, choice, beta, stable := NewState("start"), NewState("choice"), NewState("beta"), NewState("stable")
+	start.AddTransition(choice, "go")
+	choice.AddTransition(beta, "beta")
+	choice.AddTransition(stable, "stable")
+	choice.SetFlag("beta", "beta-flow")
+	choice.SetChoice("beta", "stable")
+	m.SetStates([]*State{start, choice, beta, stable})
+	m.SetStart("start")
+
+	for _, test := range []struct {
+		tenant string
+		want   string
+	}{{"acme", "beta"}, {"other", "stable"}} {
+		r := NewRunner(m)
+		r.SetFlagProvider(FlagFunc(func(ctx context.Context, flag string) bool {
+			return ctx.Value(tenant{}) == "acme"
+		}))
+		ctx := context.WithValue(context.Background(), tenant{}, test.tenant)
+		state, ok, err := r.FeedContext(ctx, "go")
+		if err != nil || !ok || state != test.want {
+			t.Fatalf("tenant %s: got %s %t %v, want %s", test.tenant, state, ok, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package dfa
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+const errInvalidFrequency = "invalid frequency record"
+
+// ReadFrequencies reads transition frequencies from CSV records of the form
+// "from,symbol,to,count", as they can be exported from a CountingTracer.
+// Counts of repeated transitions are added up.
+func ReadFrequencies(r io.Reader) (map[Hit]int64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	hits := make(map[Hit]int64)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return hits, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		count, err := strconv.ParseInt(record[3], 10, 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("%s at line %d", errInvalidFrequency, line)
+		}
+		hits[Hit{From: record[0], Symbol: record[1], To: record[2]}] += count
+	}
+}
+
+// WriteFrequencies writes transition frequencies as CSV records readable
+// by ReadFrequencies, sorted by transition.
+func WriteFrequencies(w io.Writer, hits map[Hit]int64) error {
+	keys := make(map[string]Hit, len(hits))
+	for hit := range hits {
+		keys[hit.From+"\x00"+hit.Symbol+"\x00"+hit.To] = hit
+	}
+	writer := csv.NewWriter(w)
+	for _, key := range sortedKeys(keys) {
+		hit := keys[key]
+		record := []string{hit.From, hit.Symbol, hit.To, strconv.FormatInt(hits[hit], 10)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SetFrequencies sets the weights of the transitions to their relative
+// frequency among the transitions of their state, e.g. from the hits of a
+// CountingTracer. Transitions without observations get weight 0, states
+// without any observation keep their weights. Frequencies of transitions
+// that do not exist are ignored.
+func (m *DFA) SetFrequencies(hits map[Hit]int64) {
+	totals := make(map[string]int64)
+	for hit, count := range hits {
+		if state := m.GetState(hit.From); state != nil && state.Transitions[hit.Symbol] == hit.To {
+			totals[hit.From] += count
+		}
+	}
+	for name, total := range totals {
+		if total == 0 {
+			continue
+		}
+		state := m.States[name]
+		for symbol, to := range state.Transitions {
+			count := hits[Hit{From: name, Symbol: symbol, To: to}]
+			state.SetWeight(symbol, float64(count)/float64(total))
+		}
+	}
+}
+
+// Generate produces a synthetic trace by a random walk from the start
+// state that picks every transition with a probability proportional to its
+// weight. Like Run it stops at the first final state; ok is false if the
+// walk got stuck or did not reach a final state within maxLength symbols.
+// The trace only depends on the weights and the numbers drawn from r, so
+// a source with the same seed reproduces it.
+func (m *DFA) Generate(r *rand.Rand, maxLength int) ([]string, bool) {
+	current := m.GetState(m.Start)
+	if current == nil {
+		return nil, false
+	}
+	var trace []string
+	for len(trace) < maxLength {
+		if current.Final {
+			return trace, true
+		}
+		symbol, ok := pickWeighted(r, current)
+		if !ok {
+			return trace, false
+		}
+		trace = append(trace, symbol)
+		if current = m.GetState(current.Transitions[symbol]); current == nil {
+			return trace, false
+		}
+	}
+	return trace, current.Final
+}
+
+// pickWeighted picks a transition symbol of the state with a probability
+// proportional to its weight, negative weights count as 0.
+func pickWeighted(r *rand.Rand, state *State) (string, bool) {
+	symbols := sortedSymbols(state)
+	var total float64
+	for _, symbol := range symbols {
+		total += positive(state.Weight(symbol))
+	}
+	if total <= 0 {
+		return "", false
+	}
+	point := r.Float64() * total
+	for _, symbol := range symbols {
+		weight := positive(state.Weight(symbol))
+		if point < weight {
+			return symbol, true
+		}
+		point -= weight
+	}
+	// rounding left the point behind the last symbol with a weight
+	for i := len(symbols) - 1; i >= 0; i-- {
+		if state.Weight(symbols[i]) > 0 {
+			return symbols[i], true
+		}
+	}
+	return "", false
+}
+
+// positive returns the weight or 0 if it is negative.
+func positive(weight float64) float64 {
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
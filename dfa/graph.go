@@ -0,0 +1,141 @@
+package dfa
+
+// reachableFrom returns all states reachable from the given state,
+// including the state itself.
+func (m *DFA) reachableFrom(from string) map[string]bool {
+	return m.search([]string{from}, func(name string) []string {
+		state := m.GetState(name)
+		if state == nil {
+			return nil
+		}
+		targets := make([]string, 0, len(state.Transitions))
+		for _, to := range state.Transitions {
+			targets = append(targets, to)
+		}
+		return targets
+	})
+}
+
+// coReachable returns all states from which a final state can be reached.
+func (m *DFA) coReachable() map[string]bool {
+	var finals []string
+	for name, state := range m.States {
+		if state.Final {
+			finals = append(finals, name)
+		}
+	}
+	return m.reaching(finals)
+}
+
+// reaching returns all states from which one of the targets can be reached,
+// including the targets themselves.
+func (m *DFA) reaching(targets []string) map[string]bool {
+	reverse := make(map[string][]string)
+	for name, state := range m.States {
+		for _, to := range state.Transitions {
+			reverse[to] = append(reverse[to], name)
+		}
+	}
+	return m.search(targets, func(name string) []string {
+		return reverse[name]
+	})
+}
+
+// search returns all states found breadth-first from the seeds, including
+// the seeds. The neighbors of a level are collected by the workers of the
+// DFA, so next has to be safe for concurrent use.
+func (m *DFA) search(seeds []string, next func(name string) []string) map[string]bool {
+	visited := make(map[string]bool)
+	var frontier []string
+	for _, seed := range seeds {
+		if !visited[seed] {
+			visited[seed] = true
+			frontier = append(frontier, seed)
+		}
+	}
+	for len(frontier) > 0 {
+		found := make([][]string, len(frontier))
+		parallel(m.workers, len(frontier), minChunk, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				found[i] = next(frontier[i])
+			}
+		})
+		frontier = frontier[:0:0]
+		for _, names := range found {
+			for _, name := range names {
+				if !visited[name] {
+					visited[name] = true
+					frontier = append(frontier, name)
+				}
+			}
+		}
+	}
+	return visited
+}
+
+// liveStates returns the states that are reachable from the start state
+// and from which a final state can be reached.
+func (m *DFA) liveStates() map[string]bool {
+	live := make(map[string]bool)
+	if !m.StateExists(m.Start) {
+		return live
+	}
+	coReachable := m.coReachable()
+	for name := range m.reachableFrom(m.Start) {
+		if coReachable[name] && m.StateExists(name) {
+			live[name] = true
+		}
+	}
+	return live
+}
+
+// components returns the strongly connected components of the subgraph
+// induced by the given states (Tarjan's algorithm).
+func (m *DFA) components(states map[string]bool) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var result [][]string
+	counter := 0
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = counter
+		low[name] = counter
+		counter++
+		stack = append(stack, name)
+		onStack[name] = true
+		state := m.States[name]
+		for _, symbol := range sortedSymbols(state) {
+			to := state.Transitions[symbol]
+			if !states[to] {
+				continue
+			}
+			if _, ok := index[to]; !ok {
+				connect(to)
+				low[name] = min(low[name], low[to])
+			} else if onStack[to] {
+				low[name] = min(low[name], index[to])
+			}
+		}
+		if low[name] == index[name] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			result = append(result, component)
+		}
+	}
+	for _, name := range sortedKeys(states) {
+		if _, ok := index[name]; !ok {
+			connect(name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package dfa
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	growthIterations = 1000
+	growthTolerance  = 1e-12
+)
+
+// GrowthRate estimates the dominant eigenvalue of the transition matrix
+// restricted to live states (reachable from the start and able to reach a
+// final state). A rate above 1 means the number of accepted words grows
+// exponentially with their length, a rate of 1 means polynomial growth and
+// a rate of 0 means the language is finite.
+func (m *DFA) GrowthRate() float64 {
+	rate := 0.0
+	// the dominant eigenvalue is the largest one of the strongly connected components
+	for _, component := range m.components(m.liveStates()) {
+		rate = math.Max(rate, m.componentRate(component))
+	}
+	return rate
+}
+
+// componentRate estimates the dominant eigenvalue of a strongly connected
+// component by power iteration on A+I, whose dominant eigenvalue is the one
+// of A plus 1 and which does not oscillate on periodic components.
+func (m *DFA) componentRate(component []string) float64 {
+	sort.Strings(component)
+	index := make(map[string]int, len(component))
+	for i, name := range component {
+		index[name] = i
+	}
+	vector := make([]float64, len(component))
+	for i := range vector {
+		vector[i] = 1
+	}
+	rate := 0.0
+	for iteration := 0; iteration < growthIterations; iteration++ {
+		next := make([]float64, len(component))
+		copy(next, vector)
+		for i, name := range component {
+			for _, to := range m.States[name].Transitions {
+				if j, ok := index[to]; ok {
+					next[i] += vector[j]
+				}
+			}
+		}
+		norm := 0.0
+		for _, value := range next {
+			norm = math.Max(norm, value)
+		}
+		for i := range next {
+			next[i] /= norm
+		}
+		vector = next
+		converged := math.Abs(norm-1-rate) < growthTolerance
+		rate = norm - 1
+		if converged {
+			break
+		}
+	}
+	return rate
+}
@@ -0,0 +1,67 @@
+package dfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalid is returned if a symbol can not be processed in a state.
+	ErrInvalid = errors.New("invalid transition")
+	// ErrForbidden is returned if a guard denied a transition.
+	ErrForbidden = errors.New("forbidden transition")
+	// ErrMaxSteps is returned if a run or a Runner reached the maximum
+	// number of transitions of the profile (see Profile.MaxSteps).
+	ErrMaxSteps = errors.New("maximum number of steps reached")
+)
+
+// Guard decides if a transition may be taken, e.g. by checking the caller
+// identity carried by the context. A non-nil error denies the transition.
+type Guard func(ctx context.Context, symbol string) error
+
+// SetGuard installs a guard on the transition of the given symbol. The guard
+// is called with the input symbol, which may differ from the transition
+// symbol if a classifier or matcher is installed. Step and Run evaluate it
+// with an empty context, StepContext and runners with a context carrying
+// the caller's values.
+func (s *State) SetGuard(symbol string, guard Guard) {
+	if s.guards == nil {
+		s.guards = make(map[string]Guard)
+	}
+	s.guards[symbol] = guard
+}
+
+// StepContext executes one step like Step, but also evaluates the guard of
+// the transition with the context. Errors wrap ErrInvalid if the state or
+// transition does not exist and ErrForbidden if the guard denied it, a
+// *HookError is returned if a hook failed it.
+func (m *DFA) StepContext(ctx context.Context, state, symbol string) (string, error) {
+	current := m.GetState(state)
+	if current == nil {
+		return "", fmt.Errorf("%w: %s '%s'", ErrInvalid, errStateNotExistent, state)
+	}
+	transition, to, ok := m.resolve(current, symbol)
+	if !ok {
+		m.reject(state, symbol)
+		return "", fmt.Errorf("%w: %s '%s' at state '%s'", ErrInvalid, errNoTransition, symbol, state)
+	}
+	if err := m.guard(ctx, current, transition, symbol); err != nil {
+		return "", err
+	}
+	return m.take(current, transition, symbol, to, nil)
+}
+
+// guard evaluates the guard of the transition, if any. The symbol is
+// rejected and an error wrapping ErrForbidden returned if it denies it.
+func (m *DFA) guard(ctx context.Context, state *State, transition, symbol string) error {
+	guard, ok := state.guards[transition]
+	if !ok {
+		return nil
+	}
+	if err := guard(ctx, symbol); err != nil {
+		m.reject(state.Name, symbol)
+		return fmt.Errorf("%w: %w", ErrForbidden, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package dfa
+
+import "fmt"
+
+// Context describes the transition a hook or action is executed for.
+type Context struct {
+	Machine *DFA
+	From    string
+	// Symbol is the input symbol that triggered the transition
+	Symbol string
+	To     string
+	// Vars holds the variables of the Runner taking the transition, nil
+	// outside of a runner
+	Vars Vars
+	// failure receives the error passed to Fail, nil if it is ignored
+	failure *error
+}
+
+// Fail reports that the hook or action failed, the remaining hooks are
+// skipped and the transition is reverted: Step, StepContext and Run return
+// a *HookError, a Runner also restores its variables and stays in its
+// state. Effects of the hooks beyond the runner are not undone. Only the
+// first failure counts, outside of a transition Fail is ignored.
+func (c Context) Fail(err error) {
+	if c.failure != nil && *c.failure == nil && err != nil {
+		*c.failure = err
+	}
+}
+
+// HookError is returned if a hook or action failed a transition.
+type HookError struct {
+	From   string
+	Symbol string
+	To     string
+	Err    error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("transition from '%s' for '%s' to '%s' failed: %v", e.From, e.Symbol, e.To, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// Action is executed while a transition is taken.
+type Action func(ctx Context)
+
+// OnEnter registers a handler that is executed whenever a transition enters
+// the state. Handlers of a state run in the order they were registered.
+func (m *DFA) OnEnter(state string, fn func(ctx Context)) {
+	if m.onEnter == nil {
+		m.onEnter = make(map[string][]Action)
+	}
+	m.onEnter[state] = append(m.onEnter[state], fn)
+}
+
+// OnExit registers a handler that is executed whenever a transition leaves
+// the state. Handlers of a state run in the order they were registered.
+func (m *DFA) OnExit(state string, fn func(ctx Context)) {
+	if m.onExit == nil {
+		m.onExit = make(map[string][]Action)
+	}
+	m.onExit[state] = append(m.onExit[state], fn)
+}
+
+// AddTransitionWithAction adds a transition like AddTransition that
+// executes the action whenever it is taken.
+func (s *State) AddTransitionWithAction(state *State, symbol string, action func(ctx Context)) {
+	s.AddTransition(state, symbol)
+	if s.actions == nil {
+		s.actions = make(map[string]Action)
+	}
+	s.actions[symbol] = action
+}
+
+// take executes the transition of the state into the target state for the
+// transition symbol that was matched by the input symbol: the exit handlers
+// of the state, the action of the transition and the enter handlers of the
+// target are run in this order. It returns the target state, or a
+// *HookError once one of them failed (see Context.Fail).
+func (m *DFA) take(from *State, transition, symbol, to string, vars Vars) (string, error) {
+	m.observeTransition(to)
+	if m.tracer != nil {
+		m.tracer.OnStep(from.Name, symbol, to)
+	}
+	if !m.hooked(from, transition, to) {
+		return to, nil
+	}
+	var failure error
+	ctx := Context{Machine: m, From: from.Name, Symbol: symbol, To: to, Vars: vars, failure: &failure}
+	handlers := append([]Action{}, m.onExit[from.Name]...)
+	if action := from.actions[transition]; action != nil {
+		handlers = append(handlers, action)
+	}
+	for _, fn := range append(handlers, m.onEnter[to]...) {
+		fn(ctx)
+		if failure != nil {
+			return "", &HookError{From: from.Name, Symbol: symbol, To: to, Err: failure}
+		}
+	}
+	return to, nil
+}
+
+// hooked tests if hooks or an action run when the transition is taken.
+func (m *DFA) hooked(from *State, transition, to string) bool {
+	return len(m.onExit[from.Name]) > 0 || from.actions[transition] != nil || len(m.onEnter[to]) > 0
+}
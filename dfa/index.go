@@ -0,0 +1,186 @@
+package dfa
+
+// indexEntry records one entry a state contributed to the indexes: either
+// a state between two symbols (key set) or an edge of a symbol.
+type indexEntry struct {
+	key    string
+	symbol string
+	to     string
+}
+
+// stateChanged updates the indexes and the reachability tracker after the
+// transitions of a state changed.
+func (m *DFA) stateChanged(name string) {
+	m.mu.Lock()
+	if m.Indexed && m.States[name] != nil {
+		m.reindex(name)
+	}
+	m.mu.Unlock()
+	if m.reachability != nil {
+		m.reachability.update(name)
+	}
+}
+
+// reindex updates the index entries that depend on the given state: its
+// own entries and those of the states with transitions into it. The caller
+// has to hold the lock.
+func (m *DFA) reindex(name string) {
+	affected := []string{name}
+	for source := range m.incoming[name] {
+		if source != name {
+			affected = append(affected, source)
+		}
+	}
+	for _, source := range affected {
+		m.removeContributions(source)
+		m.addContributions(source)
+	}
+}
+
+// addContributions adds the index entries caused by the transitions of a state.
+func (m *DFA) addContributions(name string) {
+	if m.States[name] != nil {
+		m.applyContributions(name, m.contributionsOf(name))
+	}
+}
+
+// contributionsOf computes the index entries caused by the transitions of
+// a state without changing the indexes.
+func (m *DFA) contributionsOf(name string) []indexEntry {
+	var entries []indexEntry
+	for symbol1, to := range m.States[name].Transitions {
+		entries = append(entries, indexEntry{symbol: symbol1, to: to})
+		target := m.States[to]
+		if target == nil {
+			continue
+		}
+		for symbol2 := range target.Transitions {
+			entries = append(entries, indexEntry{key: m.buildKey(symbol1, symbol2), to: to})
+		}
+	}
+	return entries
+}
+
+// applyContributions adds the index entries of a state to the indexes.
+func (m *DFA) applyContributions(name string, entries []indexEntry) {
+	for _, entry := range entries {
+		if entry.key != "" {
+			m.StateLookup[entry.key] = append(m.StateLookup[entry.key], entry.to)
+			continue
+		}
+		if m.incoming[entry.to] == nil {
+			m.incoming[entry.to] = make(map[string]bool)
+		}
+		m.incoming[entry.to][name] = true
+		m.EdgeLookup[entry.symbol] = append(m.EdgeLookup[entry.symbol],
+			&Edge{From: name, To: entry.to, Symbol: entry.symbol})
+	}
+	m.contributions[name] = entries
+}
+
+// removeContributions removes the index entries previously added for a state.
+func (m *DFA) removeContributions(name string) {
+	for _, entry := range m.contributions[name] {
+		if entry.key != "" {
+			m.StateLookup[entry.key] = removeOnce(m.StateLookup[entry.key], entry.to)
+			if len(m.StateLookup[entry.key]) == 0 {
+				delete(m.StateLookup, entry.key)
+			}
+			continue
+		}
+		edges := m.EdgeLookup[entry.symbol]
+		for i, edge := range edges {
+			if edge.From == name && edge.To == entry.to {
+				edges = append(edges[:i:i], edges[i+1:]...)
+				break
+			}
+		}
+		if len(edges) == 0 {
+			delete(m.EdgeLookup, entry.symbol)
+		} else {
+			m.EdgeLookup[entry.symbol] = edges
+		}
+		delete(m.incoming[entry.to], name)
+	}
+	delete(m.contributions, name)
+}
+
+// removeOnce removes the first occurrence of a value from a slice.
+func removeOnce(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i:i], values[i+1:]...)
+		}
+	}
+	return values
+}
+
+// Index is an immutable snapshot of the indexes of a DFA. Unlike the
+// Inspect methods of the DFA it never changes, so it can be shared between
+// goroutines without synchronization.
+type Index struct {
+	states map[string][]string
+	edges  map[string][]Edge
+}
+
+// BuildIndex builds a separate index of the DFA without changing it. The
+// states are indexed by the workers of the DFA (see SetWorkers).
+func (m *DFA) BuildIndex() *Index {
+	index := &Index{
+		states: make(map[string][]string),
+		edges:  make(map[string][]Edge),
+	}
+	names := sortedKeys(m.States)
+	entries := make([][]indexEntry, len(names))
+	parallel(m.workers, len(names), minChunk, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			state := m.States[names[i]]
+			for _, symbol1 := range sortedSymbols(state) {
+				to := state.Transitions[symbol1]
+				entries[i] = append(entries[i], indexEntry{symbol: symbol1, to: to})
+				target := m.States[to]
+				if target == nil {
+					continue
+				}
+				for symbol2 := range target.Transitions {
+					entries[i] = append(entries[i], indexEntry{key: indexKey(symbol1, symbol2), to: to})
+				}
+			}
+		}
+	})
+	for i, name := range names {
+		for _, entry := range entries[i] {
+			if entry.key != "" {
+				index.states[entry.key] = append(index.states[entry.key], entry.to)
+				continue
+			}
+			index.edges[entry.symbol] = append(index.edges[entry.symbol],
+				Edge{From: name, To: entry.to, Symbol: entry.symbol})
+		}
+	}
+	return index
+}
+
+// InspectStates returns all states that are in between the two symbols.
+func (x *Index) InspectStates(from, to string) []string {
+	return append([]string{}, x.states[indexKey(from, to)]...)
+}
+
+// InspectSymbols returns all edges of transitions with the symbol.
+func (x *Index) InspectSymbols(symbol string) []*Edge {
+	edges := x.edges[symbol]
+	if edges == nil {
+		return nil
+	}
+	result := make([]*Edge, len(edges))
+	for i := range edges {
+		edge := edges[i]
+		result[i] = &edge
+	}
+	return result
+}
+
+// GetSymbols returns the distinct symbols of the indexed DFA, sorted.
+func (x *Index) GetSymbols() []string {
+	return sortedKeys(x.edges)
+}
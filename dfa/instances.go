@@ -0,0 +1,68 @@
+package dfa
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+const errInvalidInstance = "invalid instance"
+
+// instanceRecord is one line of an instance export.
+type instanceRecord struct {
+	Key      string         `json:"key"`
+	Snapshot RunnerSnapshot `json:"snapshot"`
+}
+
+// ExportInstances streams the snapshots of the instances by key as newline
+// delimited JSON, one {"key": ..., "snapshot": ...} object per line, e.g. to
+// back them up or to move them to another store. Only instances the filter
+// accepts are written, a nil filter accepts all. It returns the number of
+// written instances.
+func ExportInstances(w io.Writer, instances iter.Seq2[string, RunnerSnapshot],
+	filter func(key string, snapshot RunnerSnapshot) bool) (int, error) {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	n := 0
+	for key, snapshot := range instances {
+		if filter != nil && !filter(key, snapshot) {
+			continue
+		}
+		if err := encoder.Encode(instanceRecord{Key: key, Snapshot: snapshot}); err != nil {
+			return n, fmt.Errorf("instance '%s': %w", key, err)
+		}
+		n++
+	}
+	return n, writer.Flush()
+}
+
+// ImportInstances reads instances written by ExportInstances and passes
+// them to store one by one, so an export does not have to fit into memory;
+// restore them with Runner.Restore. Empty lines are skipped. It stops at
+// the first invalid line or error of store and returns the number of stored
+// instances.
+func ImportInstances(r io.Reader, store func(key string, snapshot RunnerSnapshot) error) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record instanceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return n, fmt.Errorf("%s at line %d: %w", errInvalidInstance, line, err)
+		}
+		if record.Key == "" || len(record.Snapshot.Active) == 0 {
+			return n, fmt.Errorf("%s at line %d: key or active states missing", errInvalidInstance, line)
+		}
+		if err := store(record.Key, record.Snapshot); err != nil {
+			return n, fmt.Errorf("instance '%s' at line %d: %w", record.Key, line, err)
+		}
+		n++
+	}
+	return n, scanner.Err()
+}
@@ -0,0 +1,42 @@
+package dfa
+
+import "iter"
+
+// AllStates returns an iterator over the states of the DFA ordered by name.
+// Prefer it over ranging over States, whose representation may change.
+func (m *DFA) AllStates() iter.Seq[*State] {
+	return func(yield func(*State) bool) {
+		for _, name := range sortedKeys(m.States) {
+			if !yield(m.States[name]) {
+				return
+			}
+		}
+	}
+}
+
+// AllEdges returns an iterator over the transitions of the DFA ordered by
+// state and symbol.
+func (m *DFA) AllEdges() iter.Seq[Edge] {
+	return func(yield func(Edge) bool) {
+		for state := range m.AllStates() {
+			for symbol, to := range state.AllTransitions() {
+				if !yield(Edge{From: state.Name, To: to, Symbol: symbol}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// AllTransitions returns an iterator over the transitions of the state as
+// symbol and target state, ordered by symbol. Prefer it over ranging over
+// Transitions.
+func (s *State) AllTransitions() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, symbol := range sortedSymbols(s) {
+			if !yield(symbol, s.Transitions[symbol]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package dfa
+
+// join is the synchronization of a state advancing via the transition of
+// symbol once it received quorum of the expected symbols.
+type join struct {
+	symbol   string
+	expected []string
+	quorum   int
+}
+
+// JoinTracer is a Tracer that is also notified about symbols received by
+// joins, with the number of distinct expected symbols received so far and
+// the number needed.
+type JoinTracer interface {
+	Tracer
+	OnJoin(state, symbol string, received, quorum int)
+}
+
+// SetJoin makes the state wait until a Runner fed it all expected symbols,
+// in any order and each at least once; then the transition of the symbol is
+// taken. Expected symbols are consumed without a transition while waiting,
+// the received ones are kept in snapshots (see Runner.Snapshot) and
+// forgotten when the state is left. Passing no expected symbols removes the
+// join.
+func (s *State) SetJoin(symbol string, expected ...string) {
+	if len(expected) == 0 {
+		s.join = nil
+		return
+	}
+	s.SetQuorum(symbol, len(expected), expected...)
+}
+
+// SetQuorum makes the state wait like SetJoin, but the transition of the
+// symbol is taken once quorum of the expected symbols were received, e.g. 2
+// of 3 approvals. The quorum is clamped to the number of expected symbols.
+func (s *State) SetQuorum(symbol string, quorum int, expected ...string) {
+	if len(expected) == 0 {
+		s.join = nil
+		return
+	}
+	if quorum < 1 || quorum > len(expected) {
+		quorum = len(expected)
+	}
+	s.join = &join{symbol: symbol, expected: append([]string{}, expected...), quorum: quorum}
+}
+
+// Quorum returns the number of expected symbols the join of the state
+// needs, 0 if the state has none.
+func (s *State) Quorum() int {
+	if s.join == nil {
+		return 0
+	}
+	return s.join.quorum
+}
+
+// Join returns the symbol and the expected symbols of the join of the
+// state, ok is false if the state has none (see also Quorum).
+func (s *State) Join() (symbol string, expected []string, ok bool) {
+	if s.join == nil {
+		return "", nil, false
+	}
+	return s.join.symbol, append([]string{}, s.join.expected...), true
+}
+
+// Joined returns the expected symbols received in the current state, sorted.
+func (r *Runner) Joined() []string {
+	return sortedKeys(r.joined)
+}
+
+// collect records the token if the current state joins it. It returns the
+// symbol of the join transition once the quorum was received;
+// consumed is false if the state does not expect the token.
+func (r *Runner) collect(token string) (symbol string, consumed bool) {
+	state := r.machine.GetState(r.current)
+	if state == nil || state.join == nil || !contains(state.join.expected, token) {
+		return "", false
+	}
+	if r.joined == nil {
+		r.joined = make(map[string]bool)
+	}
+	r.joined[token] = true
+	received := 0
+	for _, expected := range state.join.expected {
+		if r.joined[expected] {
+			received++
+		}
+	}
+	if tracer, ok := r.tracer.(JoinTracer); ok {
+		tracer.OnJoin(r.current, token, received, state.join.quorum)
+	}
+	if received < state.join.quorum {
+		return "", true
+	}
+	return state.join.symbol, true
+}
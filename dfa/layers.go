@@ -0,0 +1,82 @@
+package dfa
+
+import "sort"
+
+// Layers assigns the states reachable from the start to levels by the
+// length of the longest path from the start, the start being on level 0.
+// The states of a level are sorted. Layers returns nil if the DFA has no
+// start state or if a cycle (including a self-loop) is reachable from it.
+func (m *DFA) Layers() [][]string {
+	if !m.StateExists(m.Start) {
+		return nil
+	}
+	reachable := m.reachableFrom(m.Start)
+	incoming := make(map[string]int)
+	states := 0
+	for name := range reachable {
+		state := m.GetState(name)
+		if state == nil {
+			continue
+		}
+		states++
+		for _, to := range uniqueTargets(state) {
+			if m.StateExists(to) {
+				incoming[to]++
+			}
+		}
+	}
+	// a transition into the start closes a cycle
+	if incoming[m.Start] > 0 {
+		return nil
+	}
+	level := map[string]int{m.Start: 0}
+	queue := []string{m.Start}
+	visited := 0
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, to := range uniqueTargets(m.States[name]) {
+			if !m.StateExists(to) {
+				continue
+			}
+			if level[name]+1 > level[to] {
+				level[to] = level[name] + 1
+			}
+			incoming[to]--
+			if incoming[to] == 0 {
+				queue = append(queue, to)
+			}
+		}
+	}
+	// states on or behind a cycle are never freed
+	if visited < states {
+		return nil
+	}
+	var layers [][]string
+	for name, l := range level {
+		for len(layers) <= l {
+			layers = append(layers, nil)
+		}
+		layers[l] = append(layers[l], name)
+	}
+	for _, layer := range layers {
+		sort.Strings(layer)
+	}
+	return layers
+}
+
+// uniqueTargets returns the distinct states the transitions of a state
+// lead to, sorted.
+func uniqueTargets(state *State) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, to := range state.Transitions {
+		if !seen[to] {
+			seen[to] = true
+			targets = append(targets, to)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
@@ -0,0 +1,68 @@
+package dfa
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Point is a position of a diagram in points.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// SetPosition stores the position of the state in diagrams.
+func (s *State) SetPosition(x, y float64) {
+	s.position = &Point{X: x, Y: y}
+}
+
+// Position returns the stored position of the state.
+func (s *State) Position() (Point, bool) {
+	if s.position == nil {
+		return Point{}, false
+	}
+	return *s.position, true
+}
+
+// SetRoute stores the control points the transition of the symbol is drawn
+// along, in the Graphviz spline format (3n+1 points). No points remove it.
+func (s *State) SetRoute(symbol string, points ...Point) {
+	if len(points) == 0 {
+		delete(s.routes, symbol)
+		return
+	}
+	if s.routes == nil {
+		s.routes = make(map[string][]Point)
+	}
+	s.routes[symbol] = append([]Point{}, points...)
+}
+
+// Route returns the control points of the transition of the symbol.
+func (s *State) Route(symbol string) []Point {
+	return s.routes[symbol]
+}
+
+// dotPosition renders the position of a state as DOT attribute, pinned so
+// that "neato -n" keeps it.
+func dotPosition(state *State) string {
+	if state.position == nil {
+		return ""
+	}
+	return ", pos=" + dotQuote(formatPoint(*state.position)+"!")
+}
+
+// dotRoute renders the route of a transition as DOT attribute.
+func dotRoute(route []Point) string {
+	if len(route) == 0 {
+		return ""
+	}
+	points := make([]string, len(route))
+	for i, point := range route {
+		points[i] = formatPoint(point)
+	}
+	return ", pos=" + dotQuote(strings.Join(points, " "))
+}
+
+func formatPoint(point Point) string {
+	return strconv.FormatFloat(point.X, 'g', -1, 64) + "," + strconv.FormatFloat(point.Y, 'g', -1, 64)
+}
@@ -0,0 +1,113 @@
+package dfa
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Finding is a problem reported by a lint rule.
+type Finding struct {
+	Rule    string
+	State   string
+	Symbol  string
+	Message string
+}
+
+// String formats the finding as "rule: state 'x': message".
+func (f Finding) String() string {
+	if f.State == "" {
+		return fmt.Sprintf("%s: %s", f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s: state '%s': %s", f.Rule, f.State, f.Message)
+}
+
+// Rule checks a DFA and reports findings. Custom rules only need a name
+// and a check function, Lint fills in the rule name of the findings.
+type Rule struct {
+	Name  string
+	Check func(m *DFA) []Finding
+}
+
+var (
+	// UnreachableRule reports states that can not be reached from the start,
+	// like Validate it reports none if the start state does not exist.
+	UnreachableRule = Rule{Name: "unreachable", Check: checkUnreachable}
+	// DeadStateRule reports non-final states that can not reach a final
+	// state, like Validate it reports none if there is no final state.
+	DeadStateRule = Rule{Name: "dead-state", Check: checkDeadStates}
+	// ShadowedRule reports transitions that are shadowed by the wildcard
+	// transition of their state because both lead to the same state.
+	ShadowedRule = Rule{Name: "shadowed-by-wildcard", Check: checkShadowed}
+)
+
+// DefaultRules are the rules Lint applies if no rules are given.
+var DefaultRules = []Rule{UnreachableRule, DeadStateRule, ShadowedRule}
+
+// NamingRule returns a rule reporting state names and symbols that do not
+// match the given patterns, see PatternPolicy. A nil pattern is not checked.
+func NamingRule(states, symbols *regexp.Regexp) Rule {
+	return PolicyRule(PatternPolicy(states, symbols))
+}
+
+// PolicyRule returns a rule reporting state names and symbols that violate
+// the naming policy, like Validate does for the policy of the DFA.
+func PolicyRule(policy *NamingPolicy) Rule {
+	return Rule{Name: "naming", Check: func(m *DFA) []Finding {
+		var findings []Finding
+		for _, problem := range policy.violations(m) {
+			findings = append(findings, Finding{State: problem.State, Symbol: problem.Symbol,
+				Message: problem.Message})
+		}
+		return findings
+	}}
+}
+
+// Lint checks the DFA with the given rules (DefaultRules if none are given)
+// and returns the findings in the order of the rules.
+func Lint(m *DFA, rules ...Rule) []Finding {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	var findings []Finding
+	for _, rule := range rules {
+		for _, finding := range rule.Check(m) {
+			finding.Rule = rule.Name
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func checkUnreachable(m *DFA) []Finding {
+	var findings []Finding
+	for _, name := range m.unreachableStates() {
+		findings = append(findings, Finding{State: name, Message: "not reachable from the start state"})
+	}
+	return findings
+}
+
+func checkDeadStates(m *DFA) []Finding {
+	var findings []Finding
+	for _, name := range m.deadStates() {
+		findings = append(findings, Finding{State: name, Message: "no final state can be reached"})
+	}
+	return findings
+}
+
+func checkShadowed(m *DFA) []Finding {
+	var findings []Finding
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		wildcard, ok := state.Transitions[AnySymbol]
+		if !ok {
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			if symbol != AnySymbol && state.Transitions[symbol] == wildcard {
+				findings = append(findings, Finding{State: name, Symbol: symbol,
+					Message: fmt.Sprintf("transition '%s' is shadowed by the wildcard", symbol)})
+			}
+		}
+	}
+	return findings
+}
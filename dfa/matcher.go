@@ -0,0 +1,76 @@
+package dfa
+
+import (
+	"strconv"
+	"strings"
+)
+
+const rangeDelimiter = ".."
+
+// Matcher decides if an input symbol matches the symbol of a transition.
+type Matcher func(transition, symbol string) bool
+
+// SetMatcher installs a matcher that is consulted by Step and Run when a
+// state has no transition for the exact symbol. The transitions are tried
+// in sorted order of their symbols, so the first match is deterministic.
+func (m *DFA) SetMatcher(matcher Matcher) {
+	m.matcher = matcher
+}
+
+// PrefixMatcher matches symbols that start with the transition symbol.
+func PrefixMatcher(transition, symbol string) bool {
+	return strings.HasPrefix(symbol, transition)
+}
+
+// RangeMatcher matches numeric symbols against transition symbols of the
+// form "low..high" (inclusive).
+func RangeMatcher(transition, symbol string) bool {
+	bounds := strings.SplitN(transition, rangeDelimiter, 2)
+	if len(bounds) != 2 {
+		return false
+	}
+	low, err := strconv.ParseFloat(bounds[0], 64)
+	if err != nil {
+		return false
+	}
+	high, err := strconv.ParseFloat(bounds[1], 64)
+	if err != nil {
+		return false
+	}
+	value, err := strconv.ParseFloat(symbol, 64)
+	if err != nil {
+		return false
+	}
+	return low <= value && value <= high
+}
+
+// via finds the state a (classified) symbol leads to.
+func (m *DFA) via(state *State, symbol string) (string, bool) {
+	_, to, ok := m.resolve(state, symbol)
+	return to, ok
+}
+
+// resolve returns the transition symbol and the target state that handle
+// the input symbol in the state. The symbol is classified first, then an
+// exact transition, the matcher, the wildcard transition and finally the
+// unknown symbol policy are tried. The transition symbol is empty if the
+// target was determined by the unknown symbol policy.
+func (m *DFA) resolve(state *State, symbol string) (string, string, bool) {
+	if m.classifier != nil {
+		symbol = m.classifier(symbol)
+	}
+	if to, ok := state.Transitions[symbol]; ok {
+		return symbol, to, true
+	}
+	if m.matcher != nil {
+		for _, transition := range sortedSymbols(state) {
+			if m.matcher(transition, symbol) {
+				return transition, state.Transitions[transition], true
+			}
+		}
+	}
+	if to, ok := state.Transitions[AnySymbol]; ok {
+		return AnySymbol, to, true
+	}
+	return m.unknown(state, symbol)
+}
@@ -0,0 +1,96 @@
+package dfa
+
+import (
+	"context"
+	"strconv"
+)
+
+// Minimize returns an equivalent DFA with the minimum number of states.
+// States that are unreachable or can not reach a final state are removed
+// (missing transitions reject anyway), the remaining states are merged by
+// partition refinement. A machine accepting nothing is reduced to its
+// start state.
+func (m *DFA) Minimize() *DFA {
+	minimal, _ := m.minimize(nil)
+	return minimal
+}
+
+// MinimizeContext minimizes the DFA like Minimize, but aborts with
+// *Canceled once the context is done.
+func (m *DFA) MinimizeContext(ctx context.Context) (*DFA, error) {
+	return m.minimize(ctx)
+}
+
+// minimize minimizes the DFA, the context is only checked if it is not nil.
+func (m *DFA) minimize(ctx context.Context) (*DFA, error) {
+	trimmed := m.trim()
+	if len(trimmed.States) == 0 {
+		minimal := NewDFA(m.Name)
+		minimal.SetNamer(m.namer)
+		minimal.SetState(NewState(m.Start))
+		minimal.SetStart(m.Start)
+		return minimal, nil
+	}
+	blocks, err := trimmed.partitionStates(ctx, m.progress, func(s *State) string {
+		return strconv.FormatBool(s.Final)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trimmed.Quotient(blocks), nil
+}
+
+// Equivalent tests if both DFAs accept the same language.
+func (m *DFA) Equivalent(other *DFA) bool {
+	equivalent, _ := m.equivalent(nil, other)
+	return equivalent
+}
+
+// EquivalentContext tests like Equivalent, but aborts with *Canceled once
+// the context is done.
+func (m *DFA) EquivalentContext(ctx context.Context, other *DFA) (bool, error) {
+	return m.equivalent(ctx, other)
+}
+
+// equivalent tests if both DFAs accept the same language, the context is
+// only checked if it is not nil.
+func (m *DFA) equivalent(ctx context.Context, other *DFA) (bool, error) {
+	alphabet := alphabetOf(m, other)
+	step := func(machine *DFA, state, symbol string) string {
+		if to, ok := delta(machine, state, symbol); ok {
+			return to
+		}
+		return deadKey
+	}
+	start := pairKey(m.Start, other.Start)
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	// at most all pairs of states including the dead states are visited
+	total := int64(len(m.States)+1) * int64(len(other.States)+1)
+	for done := int64(0); len(queue) > 0; done++ {
+		if done%checkEvery == 0 {
+			if err := canceled(ctx, "equivalence", done, total); err != nil {
+				return false, err
+			}
+		}
+		p, q := unpairKey(queue[0])
+		queue = queue[1:]
+		if isFinal(m, p) != isFinal(other, q) {
+			return false, nil
+		}
+		for _, symbol := range alphabet {
+			key := pairKey(step(m, p, symbol), step(other, q, symbol))
+			if !visited[key] {
+				visited[key] = true
+				queue = append(queue, key)
+			}
+		}
+	}
+	return true, nil
+}
+
+// trim returns a copy of the DFA holding only its live states and the
+// transitions between them.
+func (m *DFA) trim() *DFA {
+	return m.subMachine(m.Start, m.liveStates())
+}
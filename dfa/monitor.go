@@ -0,0 +1,128 @@
+package dfa
+
+import (
+	"math"
+	"sync"
+)
+
+// Alert is emitted by a Monitor when the score of an instance exceeds the
+// threshold.
+type Alert struct {
+	Instance string
+	// State is the state the symbol was observed in
+	State  string
+	Symbol string
+	Score  float64
+}
+
+// Monitor scores the event streams of many instances of a DFA online. For
+// every instance it follows the current state and keeps the probabilities
+// (see Score) of the last window transitions. The score of an instance is
+// 1 minus their geometric mean; a symbol without transition has the
+// probability 0 and leaves the instance in its state. It is safe for
+// concurrent use.
+type Monitor struct {
+	mu        sync.Mutex
+	machine   *DFA
+	window    int
+	threshold float64
+	alert     func(Alert)
+	instances map[string]*monitored
+}
+
+// monitored is the state of one instance of a Monitor.
+type monitored struct {
+	current string
+	// logs holds the log probabilities of the window as a ring
+	logs []float64
+	next int
+}
+
+// NewMonitor creates a monitor scoring over the last window transitions,
+// alert is called whenever a score exceeds the threshold.
+func NewMonitor(m *DFA, window int, threshold float64, alert func(Alert)) *Monitor {
+	if window < 1 {
+		window = 1
+	}
+	return &Monitor{
+		machine:   m,
+		window:    window,
+		threshold: threshold,
+		alert:     alert,
+		instances: make(map[string]*monitored),
+	}
+}
+
+// Observe feeds a symbol of an instance and returns the new score of the
+// instance. Unknown instances start at the start state.
+func (o *Monitor) Observe(instance, symbol string) float64 {
+	o.mu.Lock()
+	i, ok := o.instances[instance]
+	if !ok {
+		i = &monitored{current: o.machine.Start}
+		o.instances[instance] = i
+	}
+	from := i.current
+	probability := 0.0
+	if state := o.machine.GetState(from); state != nil {
+		if transition, to, ok := o.machine.resolve(state, symbol); ok {
+			probability = 1
+			if transition != "" {
+				probability = transitionProbability(state, transition)
+			}
+			i.current = to
+		}
+	}
+	if len(i.logs) < o.window {
+		i.logs = append(i.logs, math.Log(probability))
+	} else {
+		i.logs[i.next] = math.Log(probability)
+		i.next = (i.next + 1) % o.window
+	}
+	score := i.score()
+	o.mu.Unlock()
+	if score > o.threshold && o.alert != nil {
+		o.alert(Alert{Instance: instance, State: from, Symbol: symbol, Score: score})
+	}
+	return score
+}
+
+// Score returns the current score of an instance, 0 if it is unknown.
+func (o *Monitor) Score(instance string) float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i, ok := o.instances[instance]; ok {
+		return i.score()
+	}
+	return 0
+}
+
+// Current returns the current state of an instance.
+func (o *Monitor) Current(instance string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i, ok := o.instances[instance]
+	if !ok {
+		return "", false
+	}
+	return i.current, true
+}
+
+// Forget drops an instance, e.g. once its trace is complete.
+func (o *Monitor) Forget(instance string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.instances, instance)
+}
+
+// score returns 1 minus the geometric mean of the window.
+func (i *monitored) score() float64 {
+	if len(i.logs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, l := range i.logs {
+		sum += l
+	}
+	return 1 - math.Exp(sum/float64(len(i.logs)))
+}
@@ -0,0 +1,52 @@
+package dfa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// hashLength is the number of hex digits of the names of HashNames.
+const hashLength = 8
+
+// Namer invents the names of the states created by constructions such as
+// Union, Intersect, Concat, Complement, Compose and Minimize. It receives
+// the readable composite label of a state, names that are taken twice get
+// a "#n" suffix.
+type Namer interface {
+	Name(label string) string
+}
+
+var (
+	// ReadableNames keeps the readable composite labels, e.g. "(a.s1,b.s2)".
+	ReadableNames Namer = readableNamer{}
+	// HashNames uses short hashes of the labels, e.g. "s3f2a9c01".
+	HashNames Namer = hashNamer{}
+)
+
+// SetNamer sets the namer used by the constructions with this machine as
+// first operand, the constructed machines inherit it. nil restores the
+// default ReadableNames.
+func (m *DFA) SetNamer(namer Namer) {
+	m.namer = namer
+}
+
+// stateNamer returns the namer of the machine.
+func (m *DFA) stateNamer() Namer {
+	if m.namer == nil {
+		return ReadableNames
+	}
+	return m.namer
+}
+
+type readableNamer struct{}
+
+func (readableNamer) Name(label string) string {
+	return label
+}
+
+type hashNamer struct{}
+
+func (hashNamer) Name(label string) string {
+	hash := sha256.Sum256([]byte(label))
+	return "s" + hex.EncodeToString(hash[:])[:hashLength]
+}
@@ -0,0 +1,125 @@
+package dfa
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	// SnakeCase matches lowercase snake_case names like "awaiting_payment".
+	SnakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	// DottedSnakeCase matches dot-namespaced snake_case names like
+	// "payment.captured".
+	DottedSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+)
+
+// NamingPolicy decides which state names and symbols are allowed, so the
+// definitions of large teams stay consistent. A nil function allows all.
+type NamingPolicy struct {
+	State  func(name string) error
+	Symbol func(symbol string) error
+}
+
+// PatternPolicy returns a policy requiring state names and symbols to match
+// the patterns, e.g. SnakeCase and DottedSnakeCase. A nil pattern allows
+// all. The wildcard AnySymbol and Completion are always allowed.
+func PatternPolicy(states, symbols *regexp.Regexp) *NamingPolicy {
+	policy := &NamingPolicy{}
+	if states != nil {
+		policy.State = func(name string) error {
+			if !states.MatchString(name) {
+				return fmt.Errorf("state '%s' does not match %s", name, states)
+			}
+			return nil
+		}
+	}
+	if symbols != nil {
+		policy.Symbol = func(symbol string) error {
+			if symbol != AnySymbol && symbol != Completion && !symbols.MatchString(symbol) {
+				return fmt.Errorf("symbol '%s' does not match %s", symbol, symbols)
+			}
+			return nil
+		}
+	}
+	return policy
+}
+
+// CheckState checks a state name, a nil policy allows all.
+func (p *NamingPolicy) CheckState(name string) error {
+	if p == nil || p.State == nil {
+		return nil
+	}
+	return p.State(name)
+}
+
+// CheckSymbol checks a symbol, a nil policy allows all.
+func (p *NamingPolicy) CheckSymbol(symbol string) error {
+	if p == nil || p.Symbol == nil {
+		return nil
+	}
+	return p.Symbol(symbol)
+}
+
+// checkDefinition checks the names of the states and the symbols of their
+// transitions in a definition.
+func (p *NamingPolicy) checkDefinition(definition *Definition) error {
+	for _, state := range definition.States {
+		if err := p.CheckState(state.Name); err != nil {
+			return err
+		}
+		for _, symbol := range sortedKeys(state.Transitions) {
+			if err := p.CheckSymbol(symbol); err != nil {
+				return fmt.Errorf("state '%s': %w", state.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// violations returns the state names and symbols of the DFA violating the
+// policy in the order of the states, the messages are the errors of the
+// policy.
+func (p *NamingPolicy) violations(m *DFA) []Problem {
+	var problems []Problem
+	for _, state := range m.GetStates() {
+		if err := p.CheckState(state.Name); err != nil {
+			problems = append(problems, Problem{Kind: NamingViolation, State: state.Name, Message: err.Error()})
+		}
+		for _, symbol := range sortedSymbols(state) {
+			if err := p.CheckSymbol(symbol); err != nil {
+				problems = append(problems, Problem{Kind: NamingViolation, State: state.Name, Symbol: symbol,
+					Message: err.Error()})
+			}
+		}
+	}
+	return problems
+}
+
+// SetNamingPolicy sets the naming policy enforced by AddState and
+// DFA.AddTransition and reported by Validate. SetState, State.AddTransition
+// and FromDefinition do not check it, states set that way are only reported
+// by Validate; Registry.Load checks the definitions with the policy of the
+// registry instead. nil removes the policy.
+func (m *DFA) SetNamingPolicy(policy *NamingPolicy) {
+	m.namingPolicy = policy
+}
+
+// NamingPolicy returns the naming policy of the DFA, nil if it has none.
+func (m *DFA) NamingPolicy() *NamingPolicy {
+	return m.namingPolicy
+}
+
+// AddState sets the state like SetState if its name and the symbols of its
+// transitions comply with the naming policy.
+func (m *DFA) AddState(state *State) error {
+	if err := m.namingPolicy.CheckState(state.Name); err != nil {
+		return err
+	}
+	for _, symbol := range sortedSymbols(state) {
+		if err := m.namingPolicy.CheckSymbol(symbol); err != nil {
+			return fmt.Errorf("state '%s': %w", state.Name, err)
+		}
+	}
+	m.SetState(state)
+	return nil
+}
@@ -0,0 +1,101 @@
+package dfa
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// EventMapping names the fields of event records holding the time, the key
+// of the instance and the symbol. Nested fields are addressed with dots,
+// e.g. "payload.type". Times are strings in TimeLayout (RFC 3339 if it is
+// empty) or numbers of seconds since the Unix epoch.
+type EventMapping struct {
+	Time       string
+	Key        string
+	Symbol     string
+	TimeLayout string
+}
+
+// DefaultEventMapping maps the fields "time", "key" and "symbol".
+var DefaultEventMapping = EventMapping{Time: "time", Key: "key", Symbol: "symbol"}
+
+// ReadEventsNDJSON reads events from newline delimited JSON, one object
+// per line, as exported from data lakes. Empty lines are skipped; keys
+// that are numbers are formatted as such.
+func ReadEventsNDJSON(r io.Reader, mapping EventMapping) ([]Event, error) {
+	layout := mapping.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var events []Event
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record map[string]any
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("%s at line %d: %w", errInvalidEvent, line, err)
+		}
+		var event Event
+		var err error
+		if event.Time, err = eventTime(field(record, mapping.Time), layout); err != nil {
+			return nil, fmt.Errorf("%s at line %d: field '%s': %w", errInvalidEvent, line, mapping.Time, err)
+		}
+		var ok bool
+		if event.Key, ok = eventString(field(record, mapping.Key)); !ok {
+			return nil, fmt.Errorf("%s at line %d: field '%s' is missing", errInvalidEvent, line, mapping.Key)
+		}
+		if event.Symbol, ok = eventString(field(record, mapping.Symbol)); !ok {
+			return nil, fmt.Errorf("%s at line %d: field '%s' is missing", errInvalidEvent, line, mapping.Symbol)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// field returns the value at the dotted path of the record, nil if missing.
+func field(record map[string]any, path string) any {
+	var value any = record
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// eventString returns a string or number field as string.
+func eventString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// eventTime parses a time field in the layout or as seconds since the epoch.
+func eventTime(value any, layout string) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		return time.Parse(layout, v)
+	case json.Number:
+		seconds, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("expected string or number")
+}
@@ -0,0 +1,179 @@
+package dfa
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Intent is the record of an action about to be executed for a transition.
+type Intent struct {
+	// ID is assigned by the outbox
+	ID         string `json:"id"`
+	Machine    string `json:"machine"`
+	From       string `json:"from"`
+	Transition string `json:"transition"`
+	Symbol     string `json:"symbol"`
+	To         string `json:"to"`
+	Vars       Vars   `json:"vars,omitempty"`
+}
+
+// Outbox persists the intents of actions before they are executed and
+// marks them done afterwards, so actions interrupted by a crash can be
+// executed again (see Recover).
+type Outbox interface {
+	// Add persists the intent and returns its ID
+	Add(intent Intent) (string, error)
+	// Done marks the intent as executed
+	Done(id string) error
+	// Pending returns the intents not marked done, in the order they were added
+	Pending() ([]Intent, error)
+}
+
+// SetOutbox lets the runner record the intent of every transition action in
+// the outbox before the transition is taken and mark it done afterwards. If
+// the intent can not be added, the transition is not taken and the error is
+// returned. This gives actions at-least-once semantics together with
+// Recover, so they should be idempotent. Variables of intents have to be
+// serializable by the outbox. The outbox is shared with submachines.
+// Passing nil removes the outbox.
+func (r *Runner) SetOutbox(outbox Outbox) {
+	for level := r; level != nil; level = level.inner {
+		level.outbox = outbox
+	}
+}
+
+// Recover executes the actions of the pending intents of the machine again
+// and marks them done, e.g. after a restart. Intents of other machines are
+// left pending; enter and exit handlers are not executed.
+func Recover(m *DFA, outbox Outbox) error {
+	pending, err := outbox.Pending()
+	if err != nil {
+		return err
+	}
+	for _, intent := range pending {
+		if intent.Machine != m.Name {
+			continue
+		}
+		if state := m.GetState(intent.From); state != nil {
+			if action := state.actions[intent.Transition]; action != nil {
+				action(Context{Machine: m, From: intent.From, Symbol: intent.Symbol, To: intent.To, Vars: intent.Vars})
+			}
+		}
+		if err := outbox.Done(intent.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outboxEntry is a line of a FileOutbox.
+type outboxEntry struct {
+	Intent *Intent `json:"intent,omitempty"`
+	Done   string  `json:"done,omitempty"`
+}
+
+// FileOutbox is an Outbox appending to a file of JSON lines, which is
+// synced after every write. It is safe for concurrent use.
+type FileOutbox struct {
+	mu      sync.Mutex
+	file    *os.File
+	next    uint64
+	pending map[string]Intent
+	order   []string
+}
+
+// OpenFileOutbox opens or creates the outbox file and loads its pending
+// intents.
+func OpenFileOutbox(path string) (*FileOutbox, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	o := &FileOutbox{file: file, pending: make(map[string]Intent)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry outboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("outbox %s: line %d: %w", path, line, err)
+		}
+		if entry.Intent != nil {
+			o.pending[entry.Intent.ID] = *entry.Intent
+			o.order = append(o.order, entry.Intent.ID)
+			if id, err := strconv.ParseUint(entry.Intent.ID, 10, 64); err == nil && id >= o.next {
+				o.next = id + 1
+			}
+		} else {
+			delete(o.pending, entry.Done)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Add persists the intent.
+func (o *FileOutbox) Add(intent Intent) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	intent.ID = strconv.FormatUint(o.next, 10)
+	if err := o.write(outboxEntry{Intent: &intent}); err != nil {
+		return "", err
+	}
+	o.next++
+	o.pending[intent.ID] = intent
+	o.order = append(o.order, intent.ID)
+	return intent.ID, nil
+}
+
+// Done marks the intent as executed.
+func (o *FileOutbox) Done(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.write(outboxEntry{Done: id}); err != nil {
+		return err
+	}
+	delete(o.pending, id)
+	return nil
+}
+
+// Pending returns the intents not marked done.
+func (o *FileOutbox) Pending() ([]Intent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var pending []Intent
+	var order []string
+	for _, id := range o.order {
+		if intent, ok := o.pending[id]; ok {
+			pending = append(pending, intent)
+			order = append(order, id)
+		}
+	}
+	o.order = order
+	return pending, nil
+}
+
+// Close closes the outbox file.
+func (o *FileOutbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.file.Close()
+}
+
+func (o *FileOutbox) write(entry outboxEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := o.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
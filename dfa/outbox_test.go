@@ -0,0 +1,52 @@
+package dfa
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOutboxSubmachineActions(t *testing.T) {
+	inner := NewDFA("inner")
+	a, b := NewState("a"), NewState("b")
+	a.AddTransitionWithAction(b, "finish", func(ctx Context) {})
+	b.SetFinal(true)
+	inner.SetStates([]*State{a, b})
+	inner.SetStart("a")
+
+	outer := NewDFA("outer")
+	idle, busy, done := NewState("idle"), NewState("busy"), NewState("done")
+	idle.AddTransitionWithAction(busy, "start", func(ctx Context) {})
+	busy.SetSubmachine(inner, "exit")
+	busy.AddTransition(done, "exit")
+	done.SetFinal(true)
+	outer.SetStates([]*State{idle, busy, done})
+	outer.SetStart("idle")
+
+	for _, restore := range []bool{false, true} {
+		outbox, err := OpenFileOutbox(filepath.Join(t.TempDir(), "outbox"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewRunner(outer)
+		r.SetOutbox(outbox)
+		if _, _, err := r.Feed("start"); err != nil {
+			t.Fatal(err)
+		}
+		if restore {
+			restored := NewRunner(outer)
+			restored.SetOutbox(outbox)
+			if err := restored.Restore(r.Snapshot()); err != nil {
+				t.Fatal(err)
+			}
+			r = restored
+		}
+		if state, _, err := r.Feed("finish"); err != nil || state != "done" {
+			t.Fatal(state, err)
+		}
+		// every intent was added before its action and marked done afterwards
+		if outbox.next != 2 {
+			t.Fatalf("restore %t: %d intents recorded, want 2", restore, outbox.next)
+		}
+		outbox.Close()
+	}
+}
@@ -0,0 +1,117 @@
+package dfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RejectionCount counts the rejections of a symbol in a state.
+type RejectionCount struct {
+	State  string
+	Symbol string
+	Count  int
+}
+
+// DwellStats summarizes the visits of a state.
+type DwellStats struct {
+	State  string
+	Visits int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// OutcomeReport aggregates the results of a replay.
+type OutcomeReport struct {
+	Instances int
+	Accepted  int
+	Failed    int
+	// Ended counts the instances by the state they ended in
+	Ended map[string]int
+	// Rejections is sorted by count, most frequent first
+	Rejections []RejectionCount
+	// Dwell is sorted by state
+	Dwell []DwellStats
+}
+
+// Summarize aggregates replay results: how many instances were accepted,
+// where rejections occurred and the dwell time percentiles per state,
+// computed from the visits of states that were left again.
+func Summarize(results []*ReplayResult) *OutcomeReport {
+	report := &OutcomeReport{Ended: make(map[string]int)}
+	rejections := make(map[[2]string]int)
+	dwell := make(map[string][]time.Duration)
+	for _, result := range results {
+		report.Instances++
+		if result.Accepted {
+			report.Accepted++
+		}
+		if result.Err != nil {
+			report.Failed++
+		}
+		report.Ended[result.State]++
+		for _, rejection := range result.Rejections {
+			rejections[[2]string{rejection.State, rejection.Symbol}]++
+		}
+		for _, visit := range result.Visits {
+			dwell[visit.State] = append(dwell[visit.State], visit.Left.Sub(visit.Entered))
+		}
+	}
+	for key, count := range rejections {
+		report.Rejections = append(report.Rejections, RejectionCount{State: key[0], Symbol: key[1], Count: count})
+	}
+	sort.Slice(report.Rejections, func(i, j int) bool {
+		a, b := report.Rejections[i], report.Rejections[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		return a.Symbol < b.Symbol
+	})
+	for _, state := range sortedKeys(dwell) {
+		durations := dwell[state]
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		report.Dwell = append(report.Dwell, DwellStats{State: state, Visits: len(durations),
+			P50: percentile(durations, 50), P90: percentile(durations, 90), P99: percentile(durations, 99),
+			Max: durations[len(durations)-1]})
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String formats the report as text.
+func (r *OutcomeReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d instance(s), %d accepted, %d with errors\n", r.Instances, r.Accepted, r.Failed)
+	b.WriteString("ended in:\n")
+	for _, state := range sortedKeys(r.Ended) {
+		fmt.Fprintf(&b, "  %s\t%d\n", state, r.Ended[state])
+	}
+	if len(r.Rejections) > 0 {
+		b.WriteString("rejections:\n")
+		for _, rejection := range r.Rejections {
+			fmt.Fprintf(&b, "  %s\t%s\t%d\n", rejection.State, rejection.Symbol, rejection.Count)
+		}
+	}
+	if len(r.Dwell) > 0 {
+		b.WriteString("dwell (visits, p50, p90, p99, max):\n")
+		for _, stats := range r.Dwell {
+			fmt.Fprintf(&b, "  %s\t%d\t%s\t%s\t%s\t%s\n", stats.State, stats.Visits, stats.P50, stats.P90,
+				stats.P99, stats.Max)
+		}
+	}
+	return b.String()
+}
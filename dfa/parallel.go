@@ -0,0 +1,58 @@
+package dfa
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minChunk is the smallest number of items worth a goroutine of its own.
+const minChunk = 1024
+
+// SetWorkers sets the number of goroutines used by Index, BuildIndex,
+// Minimize, PartitionStates and the reachability analyses, e.g. for
+// machines with millions of states. Values below 1 use GOMAXPROCS. By
+// default one goroutine is used.
+func (m *DFA) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	m.workers = workers
+}
+
+// Workers returns the number of goroutines used by the DFA.
+func (m *DFA) Workers() int {
+	if m.workers < 1 {
+		return 1
+	}
+	return m.workers
+}
+
+// parallel splits the items [0, n) into chunks of at least grain items and
+// calls fn for every chunk on up to workers goroutines. It returns once all
+// chunks are done.
+func parallel(workers, n, grain int, fn func(lo, hi int)) {
+	if grain < 1 {
+		grain = 1
+	}
+	if workers > n/grain {
+		workers = n / grain
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += size {
+		hi := lo + size
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,201 @@
+package dfa
+
+import (
+	"context"
+	"sort"
+)
+
+// sinkClass is the initial class of the virtual sink that every missing
+// transition leads to during partition refinement.
+const sinkClass = "\x02sink"
+
+// PartitionStates computes the coarsest partition of the states that
+// refines the classes assigned by initial and is stable under the
+// transitions: two states stay in the same block only if they share the
+// initial class and every symbol leads both of them into the same block.
+// Missing transitions are distinguished from existing ones. The blocks
+// are computed with Hopcroft's algorithm and returned sorted.
+func (m *DFA) PartitionStates(initial func(*State) string) [][]string {
+	blocks, _ := m.partitionStates(nil, nil, initial)
+	return blocks
+}
+
+// partitionStates computes the partition like PartitionStates, aborting
+// with *Canceled once the context is done unless it is nil. Progress is
+// reported as the number of blocks out of at most one per state.
+func (m *DFA) partitionStates(ctx context.Context, progress func(done, total int64),
+	initial func(*State) string) ([][]string, error) {
+	names := sortedKeys(m.States)
+	sink := len(names)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	alphabet := alphabetOf(m)
+	// inverse[symbol][target] holds all sources of transitions into target
+	inverse := make([][][]int, len(alphabet))
+	// every symbol is worth a goroutine once there are enough states
+	grain := len(alphabet)
+	if sink >= minChunk {
+		grain = 1
+	}
+	parallel(m.workers, len(alphabet), grain, func(lo, hi int) {
+		for a := lo; a < hi; a++ {
+			symbol := alphabet[a]
+			inverse[a] = make([][]int, sink+1)
+			for i, name := range names {
+				target := sink
+				if to, ok := m.States[name].Transitions[symbol]; ok {
+					if j, exists := index[to]; exists {
+						target = j
+					}
+				}
+				inverse[a][target] = append(inverse[a][target], i)
+			}
+			inverse[a][sink] = append(inverse[a][sink], sink)
+		}
+	})
+
+	// initial partition
+	block := make([]int, sink+1)
+	var blocks [][]int
+	classes := make(map[string]int)
+	for i := 0; i <= sink; i++ {
+		class := sinkClass
+		if i < sink {
+			class = initial(m.States[names[i]])
+		}
+		id, ok := classes[class]
+		if !ok {
+			id = len(blocks)
+			classes[class] = id
+			blocks = append(blocks, nil)
+		}
+		block[i] = id
+		blocks[id] = append(blocks[id], i)
+	}
+
+	type splitter struct{ block, symbol int }
+	var work []splitter
+	pending := make(map[splitter]bool)
+	push := func(s splitter) {
+		if !pending[s] {
+			pending[s] = true
+			work = append(work, s)
+		}
+	}
+	for id := range blocks {
+		for a := range alphabet {
+			push(splitter{id, a})
+		}
+	}
+
+	for rounds := 0; len(work) > 0; rounds++ {
+		if rounds%checkEvery == 0 {
+			if err := canceled(ctx, "partition", int64(len(blocks)), int64(sink)); err != nil {
+				return nil, err
+			}
+			report(progress, int64(len(blocks)-1), int64(sink))
+		}
+		current := work[len(work)-1]
+		work = work[:len(work)-1]
+		pending[current] = false
+		// collect the predecessors of the splitter block
+		marked := make(map[int][]int)
+		for _, target := range blocks[current.block] {
+			for _, source := range inverse[current.symbol][target] {
+				marked[block[source]] = append(marked[block[source]], source)
+			}
+		}
+		touched := make([]int, 0, len(marked))
+		for id := range marked {
+			touched = append(touched, id)
+		}
+		sort.Ints(touched)
+		for _, id := range touched {
+			inside := marked[id]
+			if len(inside) == len(blocks[id]) {
+				continue
+			}
+			in := make(map[int]bool, len(inside))
+			for _, state := range inside {
+				in[state] = true
+			}
+			var outside []int
+			for _, state := range blocks[id] {
+				if !in[state] {
+					outside = append(outside, state)
+				}
+			}
+			// the block keeps the states outside, the new block gets the inside
+			blocks[id] = outside
+			created := len(blocks)
+			blocks = append(blocks, inside)
+			for _, state := range inside {
+				block[state] = created
+			}
+			for a := range alphabet {
+				if pending[splitter{id, a}] || len(inside) <= len(outside) {
+					push(splitter{created, a})
+				} else {
+					push(splitter{id, a})
+				}
+			}
+		}
+	}
+
+	var result [][]string
+	for _, members := range blocks {
+		var group []string
+		for _, state := range members {
+			if state != sink {
+				group = append(group, names[state])
+			}
+		}
+		if len(group) > 0 {
+			sort.Strings(group)
+			result = append(result, group)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0] < result[j][0]
+	})
+	report(progress, int64(sink), int64(sink))
+	return result, nil
+}
+
+// Quotient builds a new DFA in which every block of states is merged into
+// one state. The blocks have to be stable under the transitions (as the
+// result of PartitionStates is), transitions are taken from the first
+// member of a block. A block is final if any of its members is final.
+func (m *DFA) Quotient(blocks [][]string) *DFA {
+	quotient := NewDFA(m.Name)
+	quotient.SetNamer(m.namer)
+	blockName := make(map[string]string)
+	used := make(map[string]bool)
+	for _, members := range blocks {
+		name := uniqueName(m.stateNamer().Name(subsetLabel(members, func(s string) string { return s })), used)
+		state := NewState(name)
+		for _, member := range members {
+			blockName[member] = name
+			if isFinal(m, member) {
+				state.SetFinal(true)
+			}
+		}
+		quotient.SetState(state)
+	}
+	for _, members := range blocks {
+		representative := m.GetState(members[0])
+		if representative == nil {
+			continue
+		}
+		state := quotient.States[blockName[members[0]]]
+		for symbol, to := range representative.Transitions {
+			if name, ok := blockName[to]; ok {
+				state.Transitions[symbol] = name
+			}
+		}
+	}
+	quotient.SetStart(blockName[m.Start])
+	return quotient
+}
@@ -0,0 +1,79 @@
+package dfa
+
+import "fmt"
+
+const errUnknownEntryPoint = "unknown entry point"
+
+// SetEntryPoint declares a named entry point of the machine leading to the
+// state. Parent machines enter a submachine via its entry points (see
+// State.SetEntry) instead of referring to its internal states.
+func (m *DFA) SetEntryPoint(name, state string) {
+	if m.entryPoints == nil {
+		m.entryPoints = make(map[string]string)
+	}
+	m.entryPoints[name] = state
+}
+
+// EntryPoint returns the state of the named entry point.
+func (m *DFA) EntryPoint(name string) (string, bool) {
+	state, ok := m.entryPoints[name]
+	return state, ok
+}
+
+// SetExitPoint declares the state as a named exit point of the machine.
+// When a submachine reaches an exit point, the composite state embedding it
+// is left via its transition for the name instead of the exit symbol.
+func (m *DFA) SetExitPoint(state, name string) {
+	if m.exitPoints == nil {
+		m.exitPoints = make(map[string]string)
+	}
+	m.exitPoints[state] = name
+}
+
+// ExitPoint returns the name of the exit point of the state.
+func (m *DFA) ExitPoint(state string) (string, bool) {
+	name, ok := m.exitPoints[state]
+	return name, ok
+}
+
+// SetEntry lets the transition of the symbol enter the submachine of its
+// target composite state via the named entry point instead of its start.
+func (s *State) SetEntry(symbol, entryPoint string) {
+	if s.entries == nil {
+		s.entries = make(map[string]string)
+	}
+	s.entries[symbol] = entryPoint
+}
+
+// Entry returns the entry point used by the transition of the symbol.
+func (s *State) Entry(symbol string) (string, bool) {
+	entryPoint, ok := s.entries[symbol]
+	return entryPoint, ok
+}
+
+// enter positions a new runner of the submachine at the entry point, or
+// at its start if the entry point is empty.
+func enter(machine *DFA, entryPoint string) (*Runner, error) {
+	inner := newRunner(machine)
+	if entryPoint == "" {
+		return inner, nil
+	}
+	state, ok := machine.EntryPoint(entryPoint)
+	if !ok {
+		return nil, fmt.Errorf("%s '%s' of machine '%s'", errUnknownEntryPoint, entryPoint, machine.Name)
+	}
+	inner.current = state
+	return inner, nil
+}
+
+// done tests if the runner reached a final state or an exit point of its
+// machine and returns the symbol to leave the composite state with.
+func (r *Runner) done(exit string) (string, bool) {
+	if r.inner != nil {
+		return "", false
+	}
+	if name, ok := r.machine.ExitPoint(r.current); ok {
+		return name, true
+	}
+	return exit, isFinal(r.machine, r.current)
+}
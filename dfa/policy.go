@@ -0,0 +1,72 @@
+package dfa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PolicyKind tells what a policy demands of the language of a machine.
+type PolicyKind int
+
+const (
+	// MustNotAccept forbids every word the policy machine accepts
+	MustNotAccept PolicyKind = iota
+	// MustAccept demands every word the policy machine accepts
+	MustAccept
+)
+
+// Policy is a named safety requirement checked against machines.
+type Policy struct {
+	Name    string
+	Kind    PolicyKind
+	Machine *DFA
+}
+
+// PolicyViolation reports a machine violating a policy with the shortest
+// word that shows it.
+type PolicyViolation struct {
+	Machine        string
+	Policy         string
+	Kind           PolicyKind
+	Counterexample []string
+}
+
+// Error describes the violation and its counterexample.
+func (v *PolicyViolation) Error() string {
+	verb := "accepts forbidden"
+	if v.Kind == MustAccept {
+		verb = "rejects required"
+	}
+	return fmt.Sprintf("machine '%s' violates policy '%s': %s word '%s'",
+		v.Machine, v.Policy, verb, strings.Join(v.Counterexample, " "))
+}
+
+// CheckPolicies checks the language of the machine against the policies
+// and returns a violation with counterexample for every policy it breaks.
+func CheckPolicies(m *DFA, policies ...Policy) []*PolicyViolation {
+	var violations []*PolicyViolation
+	for _, policy := range policies {
+		var witness *DFA
+		if policy.Kind == MustAccept {
+			witness = product("violation", policy.Machine, m, func(p, q bool) bool { return p && !q })
+		} else {
+			witness = product("violation", policy.Machine, m, func(p, q bool) bool { return p && q })
+		}
+		all := make(map[string]bool, len(witness.States))
+		for name := range witness.States {
+			all[name] = true
+		}
+		word, found := witness.shortestWord(witness.Start, func(s string) bool { return isFinal(witness, s) }, all)
+		if found {
+			violations = append(violations, &PolicyViolation{Machine: m.Name, Policy: policy.Name,
+				Kind: policy.Kind, Counterexample: word})
+		}
+	}
+	return violations
+}
+
+// AddPolicy adds a policy every machine of the registry is checked against
+// by Load.
+func (g *Registry) AddPolicy(policy Policy) {
+	g.policies = append(g.policies, policy)
+}
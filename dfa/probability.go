@@ -0,0 +1,46 @@
+package dfa
+
+import "sort"
+
+// AcceptanceProbability returns the probability that a random word of the
+// given length is accepted, where every symbol is drawn independently from
+// dist (symbol -> probability). Symbols without a transition reject the word.
+func (m *DFA) AcceptanceProbability(dist map[string]float64, length int) float64 {
+	if !m.StateExists(m.Start) {
+		return 0
+	}
+	symbols := make([]string, 0, len(dist))
+	for symbol := range dist {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	current := map[string]float64{m.Start: 1}
+	for i := 0; i < length; i++ {
+		next := make(map[string]float64)
+		for _, name := range sortedKeys(current) {
+			for _, symbol := range symbols {
+				if to, ok := delta(m, name, symbol); ok {
+					next[to] += current[name] * dist[symbol]
+				}
+			}
+		}
+		current = next
+	}
+	var probability float64
+	for _, name := range sortedKeys(current) {
+		if isFinal(m, name) {
+			probability += current[name]
+		}
+	}
+	return probability
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+package dfa
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	errUnknownPolicyName = "unknown policy for unknown symbols"
+	errStrictProfile     = "machine violates strict profile"
+	errLogLevel          = "unknown log level"
+)
+
+// unknownPolicies names the policies for unknown symbols in profiles.
+var unknownPolicies = map[string]UnknownPolicy{
+	"reject": RejectUnknown,
+	"ignore": IgnoreUnknown,
+	"sink":   SinkUnknown,
+}
+
+// Profile bundles commonly co-configured options of a machine. It is
+// serialized with the definition and can be overridden when a Registry
+// loads the machine (see Registry.SetProfile).
+type Profile struct {
+	// Name identifies the profile, e.g. "payments-strict"
+	Name string `json:"name,omitempty"`
+	// Strict requires the machine to pass Validate when the profile is set
+	Strict bool `json:"strict,omitempty"`
+	// Unknown is the policy for unknown symbols, "reject", "ignore" or
+	// "sink" into the Sink state (see SetUnknownPolicy)
+	Unknown string `json:"unknown,omitempty"`
+	Sink    string `json:"sink,omitempty"`
+	// Metrics publishes the counters of the machine via expvar (see Publish)
+	// once the machine is set up (see SetUp)
+	Metrics bool `json:"metrics,omitempty"`
+	// Workers is the number of goroutines of analyses (see SetWorkers)
+	Workers int `json:"workers,omitempty"`
+	// MaxSteps limits the transitions of a run or a Runner (see ErrMaxSteps),
+	// 0 does not limit them
+	MaxSteps int `json:"maxSteps,omitempty"`
+	// LogLevel is the level the steps of the machine are logged at once it
+	// is set up (see SetUp), "debug", "info", "warn" or "error"; empty does
+	// not log them
+	LogLevel string `json:"logLevel,omitempty"`
+}
+
+// SetProfile applies the options of the profile and keeps it to be
+// serialized with the definition. Options not set by the profile are left
+// as they are, metrics and logging are only applied by SetUp, so reading a
+// definition has no side effects beyond the machine. An error is returned
+// if the policy for unknown symbols or the log level is unknown or a strict
+// machine has problems (see Validate); the profile is not applied then. Set
+// it once all states were set.
+func (m *DFA) SetProfile(profile *Profile) error {
+	if profile == nil {
+		m.profile = nil
+		return nil
+	}
+	policy, ok := unknownPolicies[profile.Unknown]
+	if profile.Unknown != "" && !ok {
+		return fmt.Errorf("%s '%s'", errUnknownPolicyName, profile.Unknown)
+	}
+	if _, err := logLevel(profile.LogLevel); err != nil {
+		return err
+	}
+	if profile.Strict {
+		if problems := m.Validate(); len(problems) > 0 {
+			errs := make([]error, len(problems))
+			for i, problem := range problems {
+				errs[i] = errors.New(problem.Message)
+			}
+			return fmt.Errorf("%s: %w", errStrictProfile, errors.Join(errs...))
+		}
+	}
+	if profile.Unknown != "" {
+		m.SetUnknownPolicy(policy, profile.Sink)
+	}
+	if profile.Workers != 0 {
+		m.SetWorkers(profile.Workers)
+	}
+	copied := *profile
+	m.profile = &copied
+	return nil
+}
+
+// SetUp applies the options of the profile that reach beyond the machine,
+// e.g. when a service starts it: it publishes the counters if Metrics is
+// set and logs the steps to the logger if LogLevel is set, replacing the
+// tracer (see LogTracer). Without profile nothing is done.
+func (m *DFA) SetUp(logger *slog.Logger) {
+	if m.profile == nil {
+		return
+	}
+	if m.profile.Metrics {
+		m.Publish()
+	}
+	if m.profile.LogLevel != "" && logger != nil {
+		// SetProfile only keeps valid levels
+		level, _ := logLevel(m.profile.LogLevel)
+		m.SetTracer(NewLogTracer(logger, level, m.Name))
+	}
+}
+
+// maxSteps returns the limit of transitions of the profile, 0 if none.
+func (m *DFA) maxSteps() int {
+	if m.profile == nil {
+		return 0
+	}
+	return m.profile.MaxSteps
+}
+
+// logLevel parses the log level of a profile, empty being info.
+func logLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return level, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("%s '%s'", errLogLevel, name)
+	}
+	return level, nil
+}
+
+// Profile returns a copy of the profile of the machine, nil if it has none.
+func (m *DFA) Profile() *Profile {
+	if m.profile == nil {
+		return nil
+	}
+	copied := *m.profile
+	return &copied
+}
@@ -0,0 +1,51 @@
+package dfa
+
+import "time"
+
+// Project simulates feeding the symbols without changing the runner, like
+// a dry run on a copy. It returns the states the runner passes, starting
+// with the current one, so the last state is where it would end up. The
+// simulation stops at the first symbol without a transition, then ok is
+// false. Hooks, actions and tracers are not executed.
+func (r *Runner) Project(symbols []string) (path []string, ok bool, err error) {
+	projection := r.clone()
+	path = []string{projection.current}
+	for _, symbol := range symbols {
+		current, moved, err := projection.Feed(symbol)
+		if err != nil || !moved {
+			return path, false, err
+		}
+		path = append(path, current)
+	}
+	return path, true, nil
+}
+
+// clone copies the runner and its active submachines into a dry-run
+// runner without tracer, including the collected joins, the deferred tokens
+// and the history.
+func (r *Runner) clone() *Runner {
+	c := &Runner{machine: r.machine, current: r.current, entered: r.entered}
+	r.share(c)
+	c.spans, c.outbox = nil, nil
+	c.SetDryRun(true, nil)
+	c.vars = make(Vars, len(r.vars))
+	for name, value := range r.vars {
+		c.vars[name] = value
+	}
+	c.entry = r.entry
+	c.deferred = append([]string{}, r.deferred...)
+	if r.joined != nil {
+		c.joined = make(map[string]bool, len(r.joined))
+		for symbol := range r.joined {
+			c.joined[symbol] = true
+		}
+	}
+	if r.history != nil {
+		c.history = r.history.copy(func() time.Time { return c.clock.Now() })
+	}
+	if r.inner != nil {
+		c.inner = r.inner.clone()
+		c.inner.vars = c.vars
+	}
+	return c
+}
@@ -0,0 +1,223 @@
+package dfa
+
+// ReachabilityChange lists the states whose reachability changed with an
+// edit of the machine.
+type ReachabilityChange struct {
+	// Reachable and Unreachable hold the states that became (un)reachable
+	// from the start
+	Reachable   []string
+	Unreachable []string
+	// Productive and Dead hold the states that became able (unable) to
+	// reach a final state
+	Productive []string
+	Dead       []string
+}
+
+// empty tests if nothing changed.
+func (c ReachabilityChange) empty() bool {
+	return len(c.Reachable)+len(c.Unreachable)+len(c.Productive)+len(c.Dead) == 0
+}
+
+// Reachability maintains which states are reachable from the start and
+// which can reach a final state while the machine is edited through
+// SetState, SetStart, AddTransition, RemoveTransition and SetFinal.
+// Added transitions and new final states are propagated incrementally,
+// removals are rare in editors and recompute the affected sets. Like the
+// machine it is not safe for concurrent edits.
+type Reachability struct {
+	machine     *DFA
+	onChange    func(ReachabilityChange)
+	reachable   map[string]bool
+	coReachable map[string]bool
+	// edges and final hold the last seen transitions and finality by state
+	edges map[string]map[string]string
+	final map[string]bool
+	// reverse counts the transitions between two states by target and source
+	reverse map[string]map[string]int
+}
+
+// TrackReachability starts maintaining the reachability of the states,
+// onChange (may be nil) is called after every edit that changed it.
+// Tracking again replaces the previous tracker.
+func (m *DFA) TrackReachability(onChange func(ReachabilityChange)) *Reachability {
+	r := &Reachability{machine: m, onChange: onChange}
+	r.rebuild()
+	m.reachability = r
+	return r
+}
+
+// Stop stops the maintenance, the tracker keeps its last state.
+func (r *Reachability) Stop() {
+	if r.machine.reachability == r {
+		r.machine.reachability = nil
+	}
+}
+
+// IsReachable tests if the state is reachable from the start.
+func (r *Reachability) IsReachable(state string) bool {
+	return r.reachable[state]
+}
+
+// IsDead tests if no final state can be reached from the state.
+func (r *Reachability) IsDead(state string) bool {
+	return !r.coReachable[state]
+}
+
+// rebuild takes a snapshot of the machine and computes both sets.
+func (r *Reachability) rebuild() {
+	r.edges = make(map[string]map[string]string)
+	r.final = make(map[string]bool)
+	r.reverse = make(map[string]map[string]int)
+	for name, state := range r.machine.States {
+		r.snapshot(name, state)
+	}
+	r.reachable, r.coReachable = r.compute()
+}
+
+// snapshot records the transitions and finality of a state.
+func (r *Reachability) snapshot(name string, state *State) {
+	for _, to := range r.edges[name] {
+		r.reverse[to][name]--
+		if r.reverse[to][name] == 0 {
+			delete(r.reverse[to], name)
+		}
+	}
+	delete(r.edges, name)
+	delete(r.final, name)
+	if state == nil {
+		return
+	}
+	edges := make(map[string]string, len(state.Transitions))
+	for symbol, to := range state.Transitions {
+		edges[symbol] = to
+		if r.reverse[to] == nil {
+			r.reverse[to] = make(map[string]int)
+		}
+		r.reverse[to][name]++
+	}
+	r.edges[name] = edges
+	r.final[name] = state.Final
+}
+
+// compute computes both sets from scratch.
+func (r *Reachability) compute() (map[string]bool, map[string]bool) {
+	reachable := make(map[string]bool)
+	if r.machine.StateExists(r.machine.Start) {
+		reachable = r.machine.reachableFrom(r.machine.Start)
+	}
+	return reachable, r.machine.coReachable()
+}
+
+// recompute computes both sets from scratch and reports the difference.
+func (r *Reachability) recompute() {
+	reachable, coReachable := r.compute()
+	var change ReachabilityChange
+	change.Reachable, change.Unreachable = difference(r.reachable, reachable)
+	change.Productive, change.Dead = difference(r.coReachable, coReachable)
+	r.reachable, r.coReachable = reachable, coReachable
+	r.notify(change)
+}
+
+// update processes an edit of a state.
+func (r *Reachability) update(name string) {
+	state := r.machine.GetState(name)
+	old, wasFinal := r.edges[name], r.final[name]
+	r.snapshot(name, state)
+	removed := state == nil && old != nil
+	var added []string
+	if state != nil {
+		if wasFinal && !state.Final {
+			removed = true
+		}
+		for symbol, to := range old {
+			if state.Transitions[symbol] != to {
+				removed = true
+			}
+		}
+		for symbol, to := range state.Transitions {
+			if old[symbol] != to {
+				added = append(added, to)
+			}
+		}
+	}
+	if removed {
+		r.recompute()
+		return
+	}
+	var change ReachabilityChange
+	if r.reachable[name] {
+		change.Reachable = r.propagate(added, r.reachable, func(n string) []string {
+			targets := make([]string, 0, len(r.edges[n]))
+			for _, to := range r.edges[n] {
+				targets = append(targets, to)
+			}
+			return targets
+		})
+	}
+	var seeds []string
+	if state != nil && state.Final && !wasFinal {
+		seeds = append(seeds, name)
+	}
+	for _, to := range added {
+		if r.coReachable[to] {
+			seeds = append(seeds, name)
+		}
+	}
+	change.Productive = r.propagate(seeds, r.coReachable, func(n string) []string {
+		return sortedKeys(r.reverse[n])
+	})
+	r.notify(change)
+}
+
+// propagate adds everything reachable from the seeds via next to the set
+// and returns the added states, sorted.
+func (r *Reachability) propagate(seeds []string, set map[string]bool, next func(string) []string) []string {
+	var added []string
+	queue := append([]string{}, seeds...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if set[name] {
+			continue
+		}
+		set[name] = true
+		added = append(added, name)
+		queue = append(queue, next(name)...)
+	}
+	if added == nil {
+		return nil
+	}
+	return sortedKeys(toSet(added))
+}
+
+// notify reports a change if there is one.
+func (r *Reachability) notify(change ReachabilityChange) {
+	if r.onChange != nil && !change.empty() {
+		r.onChange(change)
+	}
+}
+
+// difference returns the sorted keys only in after and only in before.
+func difference(before, after map[string]bool) ([]string, []string) {
+	var gained, lost []string
+	for _, name := range sortedKeys(after) {
+		if !before[name] {
+			gained = append(gained, name)
+		}
+	}
+	for _, name := range sortedKeys(before) {
+		if !after[name] {
+			lost = append(lost, name)
+		}
+	}
+	return gained, lost
+}
+
+// toSet converts a slice into a set.
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
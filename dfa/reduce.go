@@ -0,0 +1,125 @@
+package dfa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reduction reports how far Reduce got.
+type Reduction struct {
+	// Original and States are the number of states before and after
+	Original int
+	States   int
+	// LowerBound is a lower bound of the number of states left if all
+	// equivalent states were merged, so States-LowerBound bounds how far the
+	// result is from that
+	LowerBound int
+	// Exact tells whether all equivalent states were merged
+	Exact bool
+	// Rounds counts the merge rounds
+	Rounds int
+}
+
+// Reduce merges obviously equivalent states within the time budget, for
+// machines too large for Minimize. States are merged bottom-up if they are
+// both final or not and every symbol leads them into the same (merged)
+// states, so the result is equivalent whenever the budget runs out. Unlike
+// Minimize, dead and unreachable states are kept. The remaining budget is
+// spent on refining states top-down to bound the distance from the result
+// merging all equivalent states.
+func (m *DFA) Reduce(budget time.Duration) (*DFA, *Reduction) {
+	deadline := time.Now().Add(budget)
+	names := sortedKeys(m.States)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	alphabet := alphabetOf(m)
+	// targets[i][a] is the state symbol a leads state i to, -1 if none
+	targets := make([][]int, len(names))
+	for i, name := range names {
+		targets[i] = make([]int, len(alphabet))
+		for a, symbol := range alphabet {
+			targets[i][a] = -1
+			if to, ok := m.States[name].Transitions[symbol]; ok {
+				if j, exists := index[to]; exists {
+					targets[i][a] = j
+				}
+			}
+		}
+	}
+	final := func(i int) string {
+		return strconv.FormatBool(m.States[names[i]].Final)
+	}
+	reduction := &Reduction{Original: len(names)}
+
+	// merge bottom-up, every state starts in a class of its own
+	class := make([]int, len(names))
+	for i := range class {
+		class[i] = i
+	}
+	classes := len(names)
+	for time.Now().Before(deadline) {
+		reduction.Rounds++
+		merged := refine(targets, class, final)
+		if merged == classes {
+			break
+		}
+		classes = merged
+	}
+	reduction.States = classes
+
+	// refine top-down, starting with final and non-final states
+	bound := make([]int, len(names))
+	bounds := refine(nil, bound, final)
+	for bounds < classes && time.Now().Before(deadline) {
+		refined := refine(targets, bound, func(i int) string { return strconv.Itoa(bound[i]) })
+		if refined == bounds {
+			break
+		}
+		bounds = refined
+	}
+	reduction.LowerBound = bounds
+	reduction.Exact = bounds == classes
+
+	members := make(map[int][]string)
+	for i, name := range names {
+		members[class[i]] = append(members[class[i]], name)
+	}
+	blocks := make([][]string, 0, len(members))
+	for _, block := range members {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i][0] < blocks[j][0] })
+	return m.Quotient(blocks), reduction
+}
+
+// refine assigns every state the class of its signature: the label and the
+// classes its transitions lead to. It returns the number of classes.
+func refine(targets [][]int, class []int, label func(i int) string) int {
+	signatures := make(map[string]int)
+	next := make([]int, len(class))
+	var b strings.Builder
+	for i := range class {
+		b.Reset()
+		b.WriteString(label(i))
+		if targets != nil {
+			for _, to := range targets[i] {
+				b.WriteByte(',')
+				if to >= 0 {
+					b.WriteString(strconv.Itoa(class[to]))
+				}
+			}
+		}
+		id, ok := signatures[b.String()]
+		if !ok {
+			id = len(signatures)
+			signatures[b.String()] = id
+		}
+		next[i] = id
+	}
+	copy(class, next)
+	return len(signatures)
+}
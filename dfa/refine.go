@@ -0,0 +1,46 @@
+package dfa
+
+import "sort"
+
+// Refines checks if the spec simulates the implementation: every symbol
+// sequence the implementation is able to perform must be possible in
+// the spec, and wherever the implementation accepts the spec has to
+// accept as well. If not, the shortest violating trace is returned.
+func Refines(impl, spec *DFA) (bool, []string) {
+	type item struct {
+		key   string
+		trace []string
+	}
+	start := pairKey(impl.Start, spec.Start)
+	visited := map[string]bool{start: true}
+	queue := []item{{key: start}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		p, q := unpairKey(current.key)
+		if isFinal(impl, p) && !isFinal(spec, q) {
+			return false, current.trace
+		}
+		if !impl.StateExists(p) {
+			continue
+		}
+		symbols := make([]string, 0, len(impl.States[p].Transitions))
+		for symbol := range impl.States[p].Transitions {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		for _, symbol := range symbols {
+			trace := append(append([]string{}, current.trace...), symbol)
+			toSpec, ok := delta(spec, q, symbol)
+			if !ok {
+				return false, trace
+			}
+			key := pairKey(impl.States[p].Transitions[symbol], toSpec)
+			if !visited[key] {
+				visited[key] = true
+				queue = append(queue, item{key: key, trace: trace})
+			}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,213 @@
+package dfa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	errDuplicateMachine = "duplicate machine"
+	errMachineCycle     = "machines embed each other"
+)
+
+// Registry collects machine definitions that embed each other as
+// submachines by name. The definitions can be added in any order, Load
+// builds them in dependency order.
+type Registry struct {
+	definitions map[string]*Definition
+	policies    []Policy
+	naming      *NamingPolicy
+	progress    func(done, total int64)
+	// profiles holds the overriding profiles by machine, "" for all
+	profiles map[string]*Profile
+	// candidates holds the candidate versions rolled out by machine, rollouts
+	// the rollouts built by the last Load
+	candidates map[string]candidate
+	rollouts   map[string]*Rollout
+}
+
+// candidate is a candidate version of a machine and the percentage of
+// instances it is rolled out to.
+type candidate struct {
+	definition *Definition
+	percent    int
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{definitions: make(map[string]*Definition)}
+}
+
+// SetNamingPolicy sets the naming policy the definitions have to comply
+// with when they are loaded, it is also set on the loaded machines.
+func (g *Registry) SetNamingPolicy(policy *NamingPolicy) {
+	g.naming = policy
+}
+
+// SetProfile overrides the profile of the definition of the machine when it
+// is loaded, or of all machines if machine is empty; a profile set for a
+// machine takes precedence. A nil profile removes the profile.
+func (g *Registry) SetProfile(machine string, profile *Profile) {
+	if g.profiles == nil {
+		g.profiles = make(map[string]*Profile)
+	}
+	g.profiles[machine] = profile
+}
+
+// SetRollout rolls the candidate definition of the machine out to percent
+// (0-100) of the new instances. Load builds it next to the machine, which
+// stays the stable version, and Rollout returns the assignment. A nil
+// candidate stops the rollout.
+func (g *Registry) SetRollout(machine string, definition *Definition, percent int) {
+	if g.candidates == nil {
+		g.candidates = make(map[string]candidate)
+	}
+	if definition == nil {
+		delete(g.candidates, machine)
+		return
+	}
+	g.candidates[machine] = candidate{definition: definition, percent: percent}
+}
+
+// Rollout returns the rollout of the machine built by the last Load, nil if
+// no candidate was set for it.
+func (g *Registry) Rollout(machine string) *Rollout {
+	return g.rollouts[machine]
+}
+
+// SetProgress sets a function called with the number of loaded and of all
+// machines whenever Load built a machine.
+func (g *Registry) SetProgress(progress func(done, total int64)) {
+	g.progress = progress
+}
+
+// Add adds a definition, its name has to be unique within the registry.
+func (g *Registry) Add(definition *Definition) error {
+	if _, ok := g.definitions[definition.Name]; ok {
+		return fmt.Errorf("%s '%s'", errDuplicateMachine, definition.Name)
+	}
+	g.definitions[definition.Name] = definition
+	return nil
+}
+
+// AddDir adds the JSON definitions of all "*.json" files of a directory.
+func (g *Registry) AddDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		definition, err := JSONCodec.Decode(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := g.Add(definition); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Load builds all machines of the registry by name. Submachines are built
+// before the machines embedding them and shared between them. An error is
+// returned if a submachine is unknown, machines embed each other, a
+// machine violates a policy (as *PolicyViolation) or the naming policy.
+// Candidates set by SetRollout are built last and checked likewise.
+func (g *Registry) Load() (map[string]*DFA, error) {
+	machines := make(map[string]*DFA, len(g.definitions))
+	// visiting holds the machines on the current dependency path
+	var visiting []string
+	var load func(name string) error
+	load = func(name string) error {
+		if _, ok := machines[name]; ok {
+			return nil
+		}
+		for i, other := range visiting {
+			if other == name {
+				cycle := append(append([]string{}, visiting[i:]...), name)
+				return fmt.Errorf("%s: %s", errMachineCycle, strings.Join(cycle, " -> "))
+			}
+		}
+		definition, ok := g.definitions[name]
+		if !ok {
+			return fmt.Errorf("%s '%s'", errUnknownMachine, name)
+		}
+		if err := g.naming.checkDefinition(definition); err != nil {
+			return fmt.Errorf("machine '%s': %w", name, err)
+		}
+		visiting = append(visiting, name)
+		for _, state := range definition.States {
+			if state.Submachine == "" {
+				continue
+			}
+			if _, ok := g.definitions[state.Submachine]; !ok {
+				return fmt.Errorf("%s '%s' of state '%s' in machine '%s'",
+					errUnknownMachine, state.Submachine, state.Name, name)
+			}
+			if err := load(state.Submachine); err != nil {
+				return err
+			}
+		}
+		visiting = visiting[:len(visiting)-1]
+		m, err := g.build(name, definition, machines)
+		if err != nil {
+			return err
+		}
+		machines[name] = m
+		report(g.progress, int64(len(machines)), int64(len(g.definitions)))
+		return nil
+	}
+	for _, name := range sortedKeys(g.definitions) {
+		if err := load(name); err != nil {
+			return nil, err
+		}
+	}
+	rollouts := make(map[string]*Rollout, len(g.candidates))
+	for _, name := range sortedKeys(g.candidates) {
+		stable, ok := machines[name]
+		if !ok {
+			return nil, fmt.Errorf("%s '%s'", errUnknownMachine, name)
+		}
+		if err := g.naming.checkDefinition(g.candidates[name].definition); err != nil {
+			return nil, fmt.Errorf("candidate of machine '%s': %w", name, err)
+		}
+		candidate, err := g.build(name, g.candidates[name].definition, machines)
+		if err != nil {
+			return nil, fmt.Errorf("candidate: %w", err)
+		}
+		rollouts[name] = NewRollout(stable, candidate, g.candidates[name].percent)
+	}
+	g.rollouts = rollouts
+	return machines, nil
+}
+
+// build builds the definition of the machine with its profile overridden,
+// the submachines are taken from machines. An error is returned if a
+// submachine is unknown or the machine violates a policy.
+func (g *Registry) build(name string, definition *Definition, machines map[string]*DFA) (*DFA, error) {
+	profile, ok := g.profiles[name]
+	if !ok {
+		profile, ok = g.profiles[""]
+	}
+	if ok {
+		overridden := *definition
+		overridden.Profile = profile
+		definition = &overridden
+	}
+	m, err := fromDefinition(definition, func(name string) *DFA { return machines[name] })
+	if err != nil {
+		return nil, fmt.Errorf("machine '%s': %w", name, err)
+	}
+	if violations := CheckPolicies(m, g.policies...); len(violations) > 0 {
+		return nil, violations[0]
+	}
+	m.SetNamingPolicy(g.naming)
+	return m, nil
+}
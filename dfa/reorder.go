@@ -0,0 +1,124 @@
+package dfa
+
+import (
+	"sync"
+	"time"
+)
+
+// Reorderer restores the order of symbols arriving out of order. Symbols
+// are pushed with a sequence number and delivered in sequence; symbols
+// arriving early are held until their predecessors arrived. If a gap is
+// not closed within the timeout, the gap symbol is delivered instead of
+// the missing symbols and delivery continues with the next held symbol.
+type Reorderer struct {
+	mu        sync.Mutex
+	next      uint64
+	held      map[uint64]string
+	timeout   time.Duration
+	gapSymbol string
+	deliver   func(symbol string)
+	timer     *time.Timer
+	// generation identifies the armed timer, so a timer that fired while
+	// it was replaced does nothing
+	generation uint64
+	stopped    bool
+}
+
+// NewReorderer creates a new reorderer expecting first as the first sequence
+// number. deliver is called in order while the reorderer is locked, the gap
+// symbol on the goroutine of the gap timer, so it must not call the
+// reorderer.
+func NewReorderer(first uint64, timeout time.Duration, gapSymbol string, deliver func(symbol string)) *Reorderer {
+	return &Reorderer{
+		next:      first,
+		held:      make(map[uint64]string),
+		timeout:   timeout,
+		gapSymbol: gapSymbol,
+		deliver:   deliver,
+	}
+}
+
+// Push adds a symbol with its sequence number. It returns false for
+// symbols that were already delivered, held or skipped by a gap, and once
+// the reorderer was stopped.
+func (r *Reorderer) Push(seq uint64, symbol string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.held[seq]; ok || seq < r.next || r.stopped {
+		return false
+	}
+	r.held[seq] = symbol
+	r.drain()
+	return true
+}
+
+// Pending returns the number of symbols held back.
+func (r *Reorderer) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.held)
+}
+
+// Stop stops the gap timer, held symbols are not delivered anymore. Once
+// Stop returned, deliver is not called anymore, not even by a gap timer
+// that already fired.
+func (r *Reorderer) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// drain delivers all contiguous symbols and (re)arms the gap timer.
+func (r *Reorderer) drain() {
+	progressed := false
+	for {
+		symbol, ok := r.held[r.next]
+		if !ok {
+			break
+		}
+		delete(r.held, r.next)
+		r.next++
+		progressed = true
+		r.deliver(symbol)
+	}
+	if len(r.held) == 0 || progressed {
+		if r.timer != nil {
+			r.timer.Stop()
+			r.timer = nil
+		}
+	}
+	if len(r.held) > 0 && r.timer == nil {
+		r.generation++
+		generation := r.generation
+		r.timer = time.AfterFunc(r.timeout, func() { r.gap(generation) })
+	}
+}
+
+// gap is called when a gap was not closed in time. It does nothing if the
+// timer of the generation was stopped or replaced in the meantime.
+func (r *Reorderer) gap(generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timer == nil || generation != r.generation {
+		return
+	}
+	r.timer = nil
+	if r.stopped || len(r.held) == 0 {
+		return
+	}
+	lowest := r.next
+	first := true
+	for seq := range r.held {
+		if first || seq < lowest {
+			lowest = seq
+			first = false
+		}
+	}
+	r.next = lowest
+	r.deliver(r.gapSymbol)
+	r.drain()
+}
@@ -0,0 +1,31 @@
+package dfa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReordererStaleGapTimer(t *testing.T) {
+	var delivered []string
+	r := NewReorderer(1, time.Millisecond, "gap", func(symbol string) { delivered = append(delivered, symbol) })
+	r.Push(3, "c")
+	// let the gap timer fire while a push holds the lock, then close the gap
+	// partially, which arms a new timer
+	r.mu.Lock()
+	time.Sleep(20 * time.Millisecond)
+	r.timeout = time.Hour
+	r.held[1] = "a"
+	r.drain()
+	r.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(delivered) != 1 || delivered[0] != "a" {
+		t.Fatalf("delivered %v, want [a]", delivered)
+	}
+	if r.timer == nil {
+		t.Fatal("the stale timer dropped the armed one")
+	}
+	r.timer.Stop()
+}
@@ -0,0 +1,141 @@
+package dfa
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const errInvalidEvent = "invalid event record"
+
+// Event is a timestamped token of the instance identified by Key.
+type Event struct {
+	Time   time.Time
+	Key    string
+	Symbol string
+}
+
+// ReadEvents reads events from CSV records of the form "time,key,symbol"
+// with times in RFC 3339 format, e.g. exported from production logs.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	var events []Event
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s at line %d: %w", errInvalidEvent, line, err)
+		}
+		events = append(events, Event{Time: t, Key: record[1], Symbol: record[2]})
+	}
+}
+
+// Rejection is a token that had no transition in the state.
+type Rejection struct {
+	Time   time.Time
+	State  string
+	Symbol string
+}
+
+// Visit is a stay of an instance in a state that was left again.
+type Visit struct {
+	State   string
+	Entered time.Time
+	Left    time.Time
+}
+
+// ReplayResult is the outcome of replaying the events of one instance.
+type ReplayResult struct {
+	Key string
+	// State is the state the instance ended in, Accepted tells if it is final
+	State      string
+	Accepted   bool
+	Steps      int
+	Rejections []Rejection
+	Visits     []Visit
+	// Err is the first error returned while feeding the events
+	Err error
+}
+
+// Replay back-tests the machine against historical events: every key gets
+// its own Runner and the events are fed in the order of their times while
+// a virtual clock follows them, so timeouts (see State.SetTimeout) expire
+// where they would have expired. Timeouts still pending are expired up to
+// until unless it is zero. The results are sorted by key.
+func Replay(m *DFA, events []Event, until time.Time) []*ReplayResult {
+	ordered := append([]Event{}, events...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Time.Before(ordered[j].Time) })
+	clock := NewVirtualClock(time.Time{})
+	runners := make(map[string]*Runner)
+	results := make(map[string]*ReplayResult)
+	for i, event := range ordered {
+		if i%checkEvery == 0 {
+			report(m.progress, int64(i), int64(len(ordered)))
+		}
+		clock.Set(event.Time)
+		r, ok := runners[event.Key]
+		if !ok {
+			r = NewRunner(m)
+			r.SetClock(clock)
+			result := &ReplayResult{Key: event.Key}
+			r.SetTracer(&replayTracer{runner: r, result: result})
+			runners[event.Key], results[event.Key] = r, result
+		}
+		if _, _, err := r.Feed(event.Symbol); err != nil && results[event.Key].Err == nil {
+			results[event.Key].Err = err
+		}
+	}
+	if !until.IsZero() {
+		clock.Set(until)
+	}
+	var sorted []*ReplayResult
+	for _, key := range sortedKeys(runners) {
+		r, result := runners[key], results[key]
+		if !until.IsZero() {
+			if _, err := r.Tick(); err != nil && result.Err == nil {
+				result.Err = err
+			}
+		}
+		result.State, result.Accepted = r.Current(), r.IsFinal()
+		r.Close()
+		sorted = append(sorted, result)
+	}
+	report(m.progress, int64(len(ordered)), int64(len(ordered)))
+	return sorted
+}
+
+// replayTracer records the steps of a replayed runner in its result.
+type replayTracer struct {
+	runner *Runner
+	result *ReplayResult
+}
+
+func (t *replayTracer) OnStep(from, symbol, to string) {
+	r := t.runner
+	left := r.clock.Now()
+	// timeouts are taken when they are noticed, but left at their deadline
+	if state := r.machine.GetState(from); state != nil && state.timeout != nil &&
+		state.timeout.symbol == symbol {
+		if deadline, err := r.deadline(state.timeout); err == nil && deadline.Before(left) {
+			left = deadline
+		}
+	}
+	t.result.Steps++
+	t.result.Visits = append(t.result.Visits, Visit{State: from, Entered: r.entered, Left: left})
+}
+
+func (t *replayTracer) OnReject(state, symbol string) {
+	t.result.Rejections = append(t.result.Rejections,
+		Rejection{Time: t.runner.clock.Now(), State: state, Symbol: symbol})
+}
+
+func (t *replayTracer) OnAccept(path []string) {}
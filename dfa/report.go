@@ -0,0 +1,134 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReportStep describes the handling of one symbol during a run.
+type ReportStep struct {
+	State  string
+	Symbol string
+	// Next is the state the symbol lead to, empty if there was no transition
+	Next string
+	// Valid holds the symbols the state would have accepted
+	Valid []string
+	// Alternatives holds the valid symbols that were not taken
+	Alternatives []string
+	// Denied holds why a guard denied the transition, empty if none did
+	Denied string
+}
+
+// RunReport describes a run of the DFA in the same way Run executes it.
+type RunReport struct {
+	Start    string
+	Steps    []ReportStep
+	Accepted bool
+	// Stopped is the state where the run stopped
+	Stopped string
+	// Remaining holds the number of tokens that were not consumed
+	Remaining int
+	// Problem holds a structural problem that aborted the run
+	Problem string
+}
+
+// Report runs the tokens and records every step in a RunReport. Guards are
+// evaluated with an empty context like Run does, a denied transition
+// rejects the run.
+func (m *DFA) Report(tokens []string) *RunReport {
+	report := &RunReport{Start: m.Start, Stopped: m.Start}
+	if !m.StateExists(m.Start) {
+		report.Problem = fmt.Sprintf("start state '%s' does not exist", m.Start)
+		report.Remaining = len(tokens)
+		return report
+	}
+	current := m.Start
+	for i, token := range tokens {
+		report.Stopped = current
+		report.Remaining = len(tokens) - i
+		state := m.GetState(current)
+		if state == nil {
+			report.Problem = fmt.Sprintf("state '%s' does not exist", current)
+			return report
+		}
+		if state.Final {
+			report.Accepted = true
+			return report
+		}
+		step := ReportStep{State: current, Symbol: token, Valid: sortedSymbols(state)}
+		transition, next, ok := m.resolve(state, token)
+		for _, symbol := range step.Valid {
+			if !ok || symbol != transition {
+				step.Alternatives = append(step.Alternatives, symbol)
+			}
+		}
+		if !ok {
+			report.Steps = append(report.Steps, step)
+			return report
+		}
+		if err := m.guard(context.Background(), state, transition, token); err != nil {
+			step.Denied = err.Error()
+			report.Steps = append(report.Steps, step)
+			return report
+		}
+		step.Next = next
+		report.Steps = append(report.Steps, step)
+		current = next
+	}
+	report.Stopped = current
+	report.Remaining = 0
+	report.Accepted = true
+	return report
+}
+
+// Explain runs the tokens and returns a human-readable narrative of the run.
+func (m *DFA) Explain(tokens []string) string {
+	return m.Report(tokens).String()
+}
+
+// String formats the report as a narrative with one line per step.
+func (r *RunReport) String() string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("started at state '%s'", r.Start))
+	for _, step := range r.Steps {
+		if step.Denied != "" {
+			lines = append(lines, fmt.Sprintf("at state '%s', received '%s' -> denied: %s",
+				step.State, step.Symbol, step.Denied))
+			continue
+		}
+		if step.Next == "" {
+			lines = append(lines, fmt.Sprintf("at state '%s', received '%s' -> no transition; valid symbols here: %s",
+				step.State, step.Symbol, strings.Join(step.Valid, ", ")))
+			continue
+		}
+		line := fmt.Sprintf("at state '%s', received '%s' -> moved to '%s'", step.State, step.Symbol, step.Next)
+		if len(step.Alternatives) > 0 {
+			line += "; not taken: " + strings.Join(step.Alternatives, ", ")
+		}
+		lines = append(lines, line)
+	}
+	switch {
+	case r.Problem != "":
+		lines = append(lines, "aborted: "+r.Problem)
+	case r.Accepted && r.Remaining > 0:
+		lines = append(lines, fmt.Sprintf("reached final state '%s', %d remaining symbol(s) ignored; accepted",
+			r.Stopped, r.Remaining))
+	case r.Accepted:
+		lines = append(lines, fmt.Sprintf("consumed all symbols, ended at state '%s'; accepted", r.Stopped))
+	default:
+		lines = append(lines, fmt.Sprintf("rejected at state '%s'", r.Stopped))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sortedSymbols returns the symbols of the state's transitions in order.
+func sortedSymbols(state *State) []string {
+	symbols := make([]string, 0, len(state.Transitions))
+	for symbol := range state.Transitions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
@@ -0,0 +1,87 @@
+package dfa
+
+import (
+	"expvar"
+	"hash/crc32"
+	"sync"
+)
+
+// Rollout assigns new instances either to the stable or to the candidate
+// version of a machine. An instance key is assigned to the candidate if its
+// hash falls into the configured percentage, so the same key always gets
+// the same version for a given percentage. It is safe for concurrent use.
+type Rollout struct {
+	mu        sync.Mutex
+	stable    *DFA
+	candidate *DFA
+	percent   uint32
+	counts    [2]int64
+	metrics   *expvar.Map
+}
+
+// NewRollout creates a rollout assigning percent (0-100) of the instances
+// to the candidate.
+func NewRollout(stable, candidate *DFA, percent int) *Rollout {
+	r := &Rollout{stable: stable, candidate: candidate}
+	r.SetPercent(percent)
+	return r
+}
+
+// SetPercent changes the percentage of instances assigned to the candidate.
+func (r *Rollout) SetPercent(percent int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	r.percent = uint32(percent)
+}
+
+// Promote assigns all new instances to the candidate.
+func (r *Rollout) Promote() {
+	r.SetPercent(100)
+}
+
+// Machine returns the version for a new instance with the given key and
+// counts the assignment.
+func (r *Rollout) Machine(key string) *DFA {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	version, m := "stable", r.stable
+	if crc32.ChecksumIEEE([]byte(key))%100 < r.percent {
+		version, m = "candidate", r.candidate
+		r.counts[1]++
+	} else {
+		r.counts[0]++
+	}
+	if r.metrics != nil {
+		r.metrics.Add(version, 1)
+	}
+	return m
+}
+
+// Assigned returns the number of instances assigned to each version.
+func (r *Rollout) Assigned() (stable, candidate int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[0], r.counts[1]
+}
+
+// Publish exposes the assignments via expvar under
+// "gopher-state.<name>.rollout" split into "stable" and "candidate", and the
+// counters of the versions (see DFA.Publish) under
+// "gopher-state.<name>.stable" and "gopher-state.<name>.candidate".
+func (r *Rollout) Publish(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prefix := expvarPrefix + name
+	r.stable.publish(prefix + ".stable")
+	r.candidate.publish(prefix + ".candidate")
+	if existing, ok := expvar.Get(prefix + ".rollout").(*expvar.Map); ok {
+		r.metrics = existing
+		return
+	}
+	r.metrics = expvar.NewMap(prefix + ".rollout")
+}
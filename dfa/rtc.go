@@ -0,0 +1,72 @@
+package dfa
+
+import (
+	"context"
+	"errors"
+)
+
+// pendingToken is a token fed while the runner was processing another one.
+type pendingToken struct {
+	ctx   context.Context
+	token string
+}
+
+// SetRunToCompletion enables run-to-completion semantics: every token is
+// processed completely, including chained choices, completion transitions
+// and submachine exits, before the next one is consumed. Tokens fed
+// reentrantly, e.g. by an action or hook of the runner, are queued and
+// processed in order once the current token is done; the outer Feed then
+// returns the final state and the errors of the queued tokens. Without it,
+// reentrant tokens are processed immediately in the middle of the step.
+func (r *Runner) SetRunToCompletion(enabled bool) {
+	r.runToCompletion = enabled
+}
+
+// process feeds an external token and, in run-to-completion mode, queues
+// reentrant tokens and processes them afterwards.
+func (r *Runner) process(ctx context.Context, token string) (string, bool, error) {
+	if r.runToCompletion {
+		if r.processing {
+			r.pending = append(r.pending, pendingToken{ctx, token})
+			return r.current, false, nil
+		}
+		r.processing = true
+		defer func() { r.processing = false }()
+	}
+	current, ok, err := r.feedOne(ctx, token)
+	errs := []error{err}
+	for len(r.pending) > 0 {
+		next := r.pending[0]
+		r.pending = r.pending[1:]
+		current, _, err = r.feedOne(next.ctx, next.token)
+		errs = append(errs, err)
+	}
+	return current, ok, errors.Join(errs...)
+}
+
+// feedOne expires timeouts, feeds the token and compares the shadow
+// afterwards.
+func (r *Runner) feedOne(ctx context.Context, token string) (string, bool, error) {
+	if _, err := r.tick(ctx); err != nil {
+		return r.current, false, err
+	}
+	if r.history != nil {
+		r.history.record(token)
+	}
+	feed := r.feed
+	if r.spans != nil {
+		feed = r.traceFeed
+	}
+	if r.shadow != nil {
+		// the shadow evaluates its guards with the variables the primary saw
+		clear(r.shadow.vars)
+		for name, value := range r.vars {
+			r.shadow.vars[name] = value
+		}
+	}
+	current, ok, err := feed(ctx, token)
+	if r.shadow != nil {
+		r.compare(ctx, token)
+	}
+	return current, ok, err
+}
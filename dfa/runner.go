@@ -0,0 +1,246 @@
+package dfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Runner executes a DFA token by token, keeping track of the current state.
+// This allows to feed tokens as they arrive instead of buffering them.
+type Runner struct {
+	machine *DFA
+	current string
+	tracer  Tracer
+	// path holds the states since the last reset while a tracer is attached
+	path []string
+	// inner runs the submachine of the current state while it is active
+	inner *Runner
+	// entry is the entry point of the submachine entered next
+	entry string
+	// deferred holds the queued tokens deferred by states
+	deferred []string
+	// joined holds the symbols received by the join of the current state
+	joined map[string]bool
+	// clock measures how long the runner stays in the current state, which
+	// it entered at entered
+	clock   Clock
+	entered time.Time
+	// history records the recent tokens for window guards
+	history *History
+	// spans creates spans for tracing, shared with submachines
+	spans *spans
+	// outbox records the intents of actions
+	outbox Outbox
+	// pending holds the tokens fed while processing in run-to-completion mode
+	runToCompletion bool
+	processing      bool
+	pending         []pendingToken
+	// dryRun skips hooks and actions, record receives the skipped transitions
+	dryRun bool
+	record Action
+	// shadow follows the same tokens, fed counts them
+	shadow      *Runner
+	fed         int
+	divergences []Divergence
+	// vars holds the variables of the instance, shared with submachines
+	vars Vars
+	// flags resolves the feature flags of transitions
+	flags FlagProvider
+	// calendars holds the calendars of timeouts by name, shared with
+	// submachines
+	calendars map[string]Calendar
+	// occupied is the state the runner counts in while its machine is
+	// published, empty if it does not count
+	occupied string
+	// steps counts the transitions since the runner was created, reset or
+	// restored, limited by the profile of the machine
+	steps int
+	// dedup remembers the IDs of the recent events, nil if disabled
+	dedup *dedup
+}
+
+// NewRunner creates a new runner positioned at the start state of the DFA.
+// It counts in the occupancy of its state while the DFA is published until
+// it is closed.
+func NewRunner(m *DFA) *Runner {
+	r := newRunner(m)
+	r.occupy(r.current)
+	return r
+}
+
+// newRunner creates a new runner at the start state that does not count
+// in the occupancy yet.
+func newRunner(m *DFA) *Runner {
+	return &Runner{
+		machine: m,
+		current: m.Start,
+		vars:    make(Vars),
+		clock:   SystemClock,
+		entered: SystemClock.Now(),
+	}
+}
+
+// Feed processes one token. It returns the (new) current state and whether
+// the token lead to a transition; without a transition the runner stays in
+// its state. An error is returned if the current state does not exist.
+// While a submachine is active the token is fed to it instead; once it
+// reaches a final state the exit transition of the composite state is taken.
+// Entered choice pseudo-states are left and completion transitions taken
+// right away (see State.SetChoice and AddCompletion).
+// Tokens deferred by the current state are queued (see State.Defer), tokens
+// expected by its join are collected (see State.SetJoin).
+// Guards are evaluated with a context carrying the variables and the
+// history of the runner; use FeedContext to pass further values, e.g. the
+// caller identity.
+func (r *Runner) Feed(token string) (string, bool, error) {
+	return r.process(r.context(context.Background()), token)
+}
+
+// feed processes one token, see Feed. Guards are evaluated with the
+// context unless it is nil.
+func (r *Runner) feed(ctx context.Context, token string) (string, bool, error) {
+	if !r.machine.StateExists(r.current) {
+		return r.current, false, errors.New(errStateNotExistent)
+	}
+	if err := r.route(ctx); err != nil {
+		return r.current, false, err
+	}
+	if err := r.descend(ctx); err != nil {
+		return r.current, false, err
+	}
+	if r.inner != nil {
+		if _, ok, err := r.inner.feed(ctx, token); err != nil || !ok {
+			return r.current, false, err
+		}
+		if err := r.ascend(ctx); err != nil {
+			return r.current, false, err
+		}
+		return r.current, true, r.redeliver(ctx)
+	}
+	if symbol, consumed := r.collect(token); consumed {
+		if symbol == "" {
+			return r.current, false, nil
+		}
+		token = symbol
+	}
+	if r.deferToken(token) {
+		return r.current, false, nil
+	}
+	ok, err := r.step(ctx, token)
+	if err != nil || !ok {
+		return r.current, false, err
+	}
+	if err := r.route(ctx); err != nil {
+		return r.current, true, err
+	}
+	if err := r.descend(ctx); err != nil {
+		return r.current, false, err
+	}
+	return r.current, true, r.redeliver(ctx)
+}
+
+// step takes the transition of the current state for the symbol. An error
+// is also returned if the transition was taken but its intent could not be
+// marked done in the outbox, or wraps ErrMaxSteps once the runner took the
+// maximum number of transitions of the profile.
+func (r *Runner) step(ctx context.Context, symbol string) (bool, error) {
+	if limit := r.machine.maxSteps(); limit > 0 && r.steps >= limit {
+		return false, fmt.Errorf("%w: %d", ErrMaxSteps, limit)
+	}
+	advance := r.advance
+	if r.spans != nil {
+		advance = r.traceAdvance
+	}
+	next, ok, err := advance(ctx, symbol)
+	if !ok {
+		if err == nil && r.tracer != nil {
+			r.tracer.OnReject(r.current, symbol)
+		}
+		return false, err
+	}
+	if r.tracer != nil {
+		r.tracer.OnStep(r.current, symbol, next)
+		r.path = append(r.path, next)
+		if isFinal(r.machine, next) {
+			r.tracer.OnAccept(append([]string{}, r.path...))
+		}
+	}
+	r.current = next
+	r.steps++
+	r.occupy(next)
+	r.entered = r.clock.Now()
+	r.joined = nil
+	return true, err
+}
+
+// SetTracer attaches a tracer to the runner. It is notified of every fed
+// token and of every entry into a final state with the states entered since
+// the last reset. Passing nil removes the tracer.
+func (r *Runner) SetTracer(tracer Tracer) {
+	r.tracer = tracer
+	r.path = []string{r.current}
+}
+
+// Current returns the current state.
+func (r *Runner) Current() string {
+	return r.current
+}
+
+// IsFinal tests if the current state is a final state and no submachine
+// is active.
+func (r *Runner) IsFinal() bool {
+	return r.inner == nil && isFinal(r.machine, r.current)
+}
+
+// Reset positions the runner at the start state again.
+func (r *Runner) Reset() {
+	r.current = r.machine.Start
+	r.occupy(r.current)
+	r.path = []string{r.current}
+	r.inner.release()
+	r.inner = nil
+	r.entry = ""
+	r.deferred = nil
+	r.pending = nil
+	r.joined = nil
+	r.steps = 0
+	r.dedup.reset(nil)
+	r.entered = r.clock.Now()
+	r.vars = make(Vars)
+	if r.history != nil {
+		r.history.rings = make(map[string]*ring)
+	}
+	if r.shadow != nil {
+		r.shadow.Reset()
+		r.fed = 0
+	}
+}
+
+// Close stops counting the runner and its active submachines in the
+// occupancy of their states (see DFA.Publish), call it once the instance is
+// discarded. Feeding the runner afterwards counts it again.
+func (r *Runner) Close() {
+	r.release()
+}
+
+// occupy counts the runner in the state instead of the state it counted in
+// if its machine is published, dry-run runners do not count.
+func (r *Runner) occupy(state string) {
+	if r.dryRun {
+		return
+	}
+	if r.machine.occupy(r.occupied, state) {
+		r.occupied = state
+	}
+}
+
+// release stops counting the runner and its active submachines.
+func (r *Runner) release() {
+	for level := r; level != nil; level = level.inner {
+		if level.machine.occupy(level.occupied, "") {
+			level.occupied = ""
+		}
+	}
+}
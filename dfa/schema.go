@@ -0,0 +1,405 @@
+package dfa
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/breskos/gopher-state/expr"
+)
+
+// DefinitionSchema is the JSON Schema of the definition format read by
+// Unmarshal and written by Marshal.
+const DefinitionSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/breskos/gopher-state/definition.schema.json",
+  "title": "gopher-state machine definition",
+  "type": "object",
+  "required": ["name", "start", "states"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string"},
+    "start": {"type": "string"},
+    "alphabet": {"type": "array", "items": {"type": "string"}},
+    "entryPoints": {"type": "object", "additionalProperties": {"type": "string"}},
+    "exitPoints": {"type": "object", "additionalProperties": {"type": "string"}},
+    "completionEvent": {"type": "string"},
+    "profile": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string"},
+        "strict": {"type": "boolean"},
+        "unknown": {"enum": ["reject", "ignore", "sink"]},
+        "sink": {"type": "string"},
+        "metrics": {"type": "boolean"},
+        "workers": {"type": "integer"}
+      }
+    },
+    "states": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "final": {"type": "boolean"},
+          "transitions": {"type": "object", "additionalProperties": {"type": "string"}},
+          "weights": {"type": "object", "additionalProperties": {"type": "number"}},
+          "submachine": {"type": "string"},
+          "exit": {"type": "string"},
+          "position": {"$ref": "#/$defs/point"},
+          "routes": {"type": "object", "additionalProperties": {"type": "array", "items": {"$ref": "#/$defs/point"}}},
+          "guards": {"type": "object", "additionalProperties": {"type": "string"}},
+          "assign": {"type": "object", "additionalProperties": {"type": "string"}},
+          "choice": {"type": "array", "items": {"type": "string"}},
+          "entries": {"type": "object", "additionalProperties": {"type": "string"}},
+          "flags": {"type": "object", "additionalProperties": {"type": "string"}},
+          "defer": {"type": "array", "items": {"type": "string"}},
+          "join": {
+            "type": "object",
+            "required": ["symbol", "expected"],
+            "additionalProperties": false,
+            "properties": {
+              "symbol": {"type": "string"},
+              "expected": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+              "quorum": {"type": "integer", "minimum": 1}
+            }
+          },
+          "timeout": {
+            "type": "object",
+            "required": ["after", "symbol"],
+            "additionalProperties": false,
+            "properties": {
+              "after": {"type": "string"},
+              "symbol": {"type": "string"},
+              "calendar": {"type": "string"}
+            }
+          }
+        }
+      }
+    }
+  },
+  "$defs": {
+    "point": {
+      "type": "object",
+      "required": ["x", "y"],
+      "additionalProperties": false,
+      "properties": {"x": {"type": "number"}, "y": {"type": "number"}}
+    }
+  }
+}`
+
+// ValidateDefinition checks a JSON machine definition against
+// DefinitionSchema and the references between its states: state names
+// have to be unique and the start, all transition targets and the states of
+// entry and exit points have to be states of the definition. Guard and
+// assignment expressions have to parse. Every error names the JSON path it
+// refers to.
+func ValidateDefinition(raw []byte) []error {
+	var document any
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return []error{fmt.Errorf("$: %w", err)}
+	}
+	v := &definitionValidator{}
+	root, ok := v.object("$", document, []string{"name", "start", "states"},
+		[]string{"name", "start", "alphabet", "states", "entryPoints", "exitPoints",
+			"completionEvent", "profile"})
+	if !ok {
+		return v.errors
+	}
+	v.string("$.name", root["name"], false)
+	v.strings("$.alphabet", root["alphabet"])
+	v.string("$.completionEvent", root["completionEvent"], false)
+	if profileValue, present := root["profile"]; present {
+		if profile, ok := v.object("$.profile", profileValue, nil,
+			[]string{"name", "strict", "unknown", "sink", "metrics", "workers", "maxSteps", "logLevel"}); ok {
+			v.string("$.profile.name", profile["name"], false)
+			v.string("$.profile.sink", profile["sink"], false)
+			if level, ok := v.string("$.profile.logLevel", profile["logLevel"], false); ok {
+				if _, err := logLevel(level); err != nil {
+					v.fail("$.profile.logLevel", `expected "debug", "info", "warn" or "error"`)
+				}
+			}
+			if unknown, ok := v.string("$.profile.unknown", profile["unknown"], false); ok {
+				if _, known := unknownPolicies[unknown]; !known {
+					v.fail("$.profile.unknown", `expected "reject", "ignore" or "sink"`)
+				}
+			}
+			for _, option := range []string{"strict", "metrics"} {
+				if value, present := profile[option]; present {
+					if _, ok := value.(bool); !ok {
+						v.fail("$.profile."+option, "expected boolean")
+					}
+				}
+			}
+			for _, option := range []string{"workers", "maxSteps"} {
+				if value, present := profile[option]; present {
+					if n, ok := value.(float64); !ok || n != float64(int(n)) {
+						v.fail("$.profile."+option, "expected integer")
+					}
+				}
+			}
+		}
+	}
+	entryPoints := v.names("$.entryPoints", root["entryPoints"])
+	exitPoints := v.names("$.exitPoints", root["exitPoints"])
+	states, _ := root["states"].([]any)
+	if _, present := root["states"]; present && states == nil {
+		v.fail("$.states", "expected array")
+	}
+	names := make(map[string]bool)
+	var targets []reference
+	for i, item := range states {
+		path := "$.states[" + strconv.Itoa(i) + "]"
+		state, ok := v.object(path, item, []string{"name"},
+			[]string{"name", "final", "transitions", "weights", "submachine", "exit", "position", "routes",
+				"guards", "assign", "choice", "entries", "flags", "defer", "join",
+				"timeout"})
+		if !ok {
+			continue
+		}
+		if name, ok := v.string(path+".name", state["name"], true); ok {
+			if names[name] {
+				v.fail(path+".name", fmt.Sprintf("%s '%s'", errDuplicateState, name))
+			}
+			names[name] = true
+		}
+		v.string(path+".submachine", state["submachine"], false)
+		v.string(path+".exit", state["exit"], false)
+		v.strings(path+".choice", state["choice"])
+		v.names(path+".entries", state["entries"])
+		v.names(path+".flags", state["flags"])
+		v.strings(path+".defer", state["defer"])
+		if timeoutValue, present := state["timeout"]; present {
+			if timeout, ok := v.object(path+".timeout", timeoutValue, []string{"after", "symbol"},
+				[]string{"after", "symbol", "calendar"}); ok {
+				if after, ok := v.string(path+".timeout.after", timeout["after"], false); ok {
+					if d, err := time.ParseDuration(after); err != nil || d <= 0 {
+						v.fail(path+".timeout.after", "expected positive duration")
+					}
+				}
+				v.string(path+".timeout.symbol", timeout["symbol"], false)
+				v.string(path+".timeout.calendar", timeout["calendar"], false)
+			}
+		}
+		if joinValue, present := state["join"]; present {
+			if join, ok := v.object(path+".join", joinValue, []string{"symbol", "expected"},
+				[]string{"symbol", "expected", "quorum"}); ok {
+				v.string(path+".join.symbol", join["symbol"], false)
+				v.strings(path+".join.expected", join["expected"])
+				if expected, ok := join["expected"].([]any); ok && len(expected) == 0 {
+					v.fail(path+".join.expected", "expected non-empty array")
+				}
+				if quorum, present := join["quorum"]; present {
+					if n, ok := quorum.(float64); !ok || n < 1 || n != float64(int(n)) {
+						v.fail(path+".join.quorum", "expected positive integer")
+					}
+				}
+			}
+		}
+		if position, present := state["position"]; present {
+			v.point(path+".position", position)
+		}
+		if routes, present := state["routes"]; present {
+			if entries, ok := routes.(map[string]any); ok {
+				for _, symbol := range sortedKeys(entries) {
+					routePath := path + ".routes" + jsonPathKey(symbol)
+					points, ok := entries[symbol].([]any)
+					if !ok {
+						v.fail(routePath, "expected array")
+						continue
+					}
+					for j, point := range points {
+						v.point(routePath+"["+strconv.Itoa(j)+"]", point)
+					}
+				}
+			} else {
+				v.fail(path+".routes", "expected object")
+			}
+		}
+		v.expressions(path+".guards", state["guards"], func(source string) error {
+			_, err := expr.Parse(source)
+			return err
+		})
+		v.expressions(path+".assign", state["assign"], func(source string) error {
+			_, err := expr.ParseAssignment(source)
+			return err
+		})
+		if final, present := state["final"]; present {
+			if _, ok := final.(bool); !ok {
+				v.fail(path+".final", "expected boolean")
+			}
+		}
+		if transitions, present := state["transitions"]; present {
+			if entries, ok := transitions.(map[string]any); ok {
+				for _, symbol := range sortedKeys(entries) {
+					entryPath := path + ".transitions" + jsonPathKey(symbol)
+					if to, ok := v.string(entryPath, entries[symbol], false); ok {
+						targets = append(targets, reference{entryPath, to})
+					}
+				}
+			} else {
+				v.fail(path+".transitions", "expected object")
+			}
+		}
+		if weights, present := state["weights"]; present {
+			if entries, ok := weights.(map[string]any); ok {
+				for _, symbol := range sortedKeys(entries) {
+					if _, ok := entries[symbol].(float64); !ok {
+						v.fail(path+".weights"+jsonPathKey(symbol), "expected number")
+					}
+				}
+			} else {
+				v.fail(path+".weights", "expected object")
+			}
+		}
+	}
+	if start, ok := v.string("$.start", root["start"], false); ok && states != nil && !names[start] {
+		v.fail("$.start", fmt.Sprintf("%s '%s'", errStateNotExistent, start))
+	}
+	for _, name := range sortedKeys(entryPoints) {
+		if state, ok := entryPoints[name].(string); ok {
+			targets = append(targets, reference{"$.entryPoints" + jsonPathKey(name), state})
+		}
+	}
+	for _, state := range sortedKeys(exitPoints) {
+		targets = append(targets, reference{"$.exitPoints" + jsonPathKey(state), state})
+	}
+	for _, target := range targets {
+		if !names[target.state] {
+			v.fail(target.path, fmt.Sprintf("%s '%s'", errStateNotExistent, target.state))
+		}
+	}
+	return v.errors
+}
+
+// reference is a state name found at a path of a definition.
+type reference struct {
+	path  string
+	state string
+}
+
+// definitionValidator collects the errors found in a definition.
+type definitionValidator struct {
+	errors []error
+}
+
+func (v *definitionValidator) fail(path, message string) {
+	v.errors = append(v.errors, fmt.Errorf("%s: %s", path, message))
+}
+
+// object checks that the value is an object with the required and only
+// the allowed properties.
+func (v *definitionValidator) object(path string, value any, required, allowed []string) (map[string]any, bool) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		v.fail(path, "expected object")
+		return nil, false
+	}
+	for _, property := range required {
+		if _, ok := object[property]; !ok {
+			v.fail(path, fmt.Sprintf("missing property '%s'", property))
+		}
+	}
+	for _, property := range sortedKeys(object) {
+		if !contains(allowed, property) {
+			v.fail(path+"."+property, "unknown property")
+		}
+	}
+	return object, true
+}
+
+// point checks that the value is a point with numeric coordinates.
+func (v *definitionValidator) point(path string, value any) {
+	point, ok := v.object(path, value, []string{"x", "y"}, []string{"x", "y"})
+	if !ok {
+		return
+	}
+	for _, axis := range []string{"x", "y"} {
+		if _, present := point[axis]; !present {
+			continue
+		}
+		if _, ok := point[axis].(float64); !ok {
+			v.fail(path+"."+axis, "expected number")
+		}
+	}
+}
+
+// string checks that a present value is a string, optionally non-empty.
+func (v *definitionValidator) string(path string, value any, nonEmpty bool) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	s, ok := value.(string)
+	if !ok {
+		v.fail(path, "expected string")
+		return "", false
+	}
+	if nonEmpty && s == "" {
+		v.fail(path, "expected non-empty string")
+		return "", false
+	}
+	return s, true
+}
+
+// strings checks that a present value is an array of strings.
+func (v *definitionValidator) strings(path string, value any) {
+	if value == nil {
+		return
+	}
+	items, ok := value.([]any)
+	if !ok {
+		v.fail(path, "expected array")
+		return
+	}
+	for i, item := range items {
+		if _, ok := item.(string); !ok {
+			v.fail(path+"["+strconv.Itoa(i)+"]", "expected string")
+		}
+	}
+}
+
+// names checks that a present value is an object of strings and returns it.
+func (v *definitionValidator) names(path string, value any) map[string]any {
+	if value == nil {
+		return nil
+	}
+	entries, ok := value.(map[string]any)
+	if !ok {
+		v.fail(path, "expected object")
+		return nil
+	}
+	for _, key := range sortedKeys(entries) {
+		v.string(path+jsonPathKey(key), entries[key], false)
+	}
+	return entries
+}
+
+// expressions checks that a present value is an object of strings that
+// parse with the function.
+func (v *definitionValidator) expressions(path string, value any, parse func(string) error) {
+	if value == nil {
+		return
+	}
+	entries, ok := value.(map[string]any)
+	if !ok {
+		v.fail(path, "expected object")
+		return
+	}
+	for _, symbol := range sortedKeys(entries) {
+		entryPath := path + jsonPathKey(symbol)
+		if source, ok := v.string(entryPath, entries[symbol], false); ok {
+			if err := parse(source); err != nil {
+				v.fail(entryPath, err.Error())
+			}
+		}
+	}
+}
+
+// jsonPathKey formats an object key as a JSON path segment.
+func jsonPathKey(key string) string {
+	return "[" + strconv.Quote(key) + "]"
+}
@@ -0,0 +1,88 @@
+package dfa
+
+import (
+	"math"
+	"time"
+)
+
+// rejectedFactor scales the likelihood of traces that end outside a final state.
+const rejectedFactor = 0.5
+
+// SetExpectedDwell sets how long a trace usually stays in the state, it is
+// compared with the observed dwell times by ScoreTimed.
+func (s *State) SetExpectedDwell(dwell time.Duration) {
+	s.dwell = dwell
+}
+
+// Score returns an anomaly score between 0 (expected) and 1 (impossible)
+// for a trace. The probability of a transition is its weight relative to
+// the weights of all transitions of its state (uniform if no weights are
+// set). The score is 1 minus the geometric mean of the probabilities of
+// the transitions taken, halved in likelihood if the trace does not end in
+// a final state. Traces with a symbol without transition score 1.
+func (m *DFA) Score(tokens []string) float64 {
+	return m.ScoreTimed(tokens, nil)
+}
+
+// ScoreTimed scores a trace like Score, additionally comparing the time
+// spent in every state before the next symbol (dwell[i] for tokens[i])
+// with the expected dwell of the state. Each comparison contributes the
+// ratio of the shorter to the longer duration like a probability.
+// Missing dwell times and states without expected dwell are not compared.
+func (m *DFA) ScoreTimed(tokens []string, dwell []time.Duration) float64 {
+	current := m.GetState(m.Start)
+	if current == nil {
+		return 1
+	}
+	logLikelihood := 0.0
+	factors := 0
+	for i, token := range tokens {
+		transition, to, ok := m.resolve(current, token)
+		if !ok {
+			return 1
+		}
+		if transition != "" {
+			logLikelihood += math.Log(transitionProbability(current, transition))
+			factors++
+		}
+		if i < len(dwell) && current.dwell > 0 {
+			logLikelihood += math.Log(dwellRatio(dwell[i], current.dwell))
+			factors++
+		}
+		if current = m.GetState(to); current == nil {
+			return 1
+		}
+	}
+	likelihood := 1.0
+	if factors > 0 {
+		likelihood = math.Exp(logLikelihood / float64(factors))
+	}
+	if !current.Final {
+		likelihood *= rejectedFactor
+	}
+	return 1 - likelihood
+}
+
+// transitionProbability returns the weight of the transition relative to
+// the weights of all transitions of the state.
+func transitionProbability(state *State, transition string) float64 {
+	var total float64
+	for symbol := range state.Transitions {
+		total += positive(state.Weight(symbol))
+	}
+	if total == 0 {
+		return 0
+	}
+	return positive(state.Weight(transition)) / total
+}
+
+// dwellRatio returns the ratio of the shorter to the longer duration.
+func dwellRatio(observed, expected time.Duration) float64 {
+	if observed <= 0 {
+		return 0
+	}
+	if observed > expected {
+		return float64(expected) / float64(observed)
+	}
+	return float64(observed) / float64(expected)
+}
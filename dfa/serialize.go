@@ -0,0 +1,321 @@
+package dfa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	errDuplicateState  = "duplicate state"
+	errEmptyDefinition = "empty definition"
+	errUnknownMachine  = "unknown machine"
+)
+
+// Definition is the serialized form of a DFA.
+type Definition struct {
+	Name     string            `json:"name"`
+	Start    string            `json:"start"`
+	Alphabet []string          `json:"alphabet,omitempty"`
+	States   []StateDefinition `json:"states"`
+	// EntryPoints holds states by entry point name, ExitPoints exit point
+	// names by state (see DFA.SetEntryPoint and SetExitPoint)
+	EntryPoints map[string]string `json:"entryPoints,omitempty"`
+	ExitPoints  map[string]string `json:"exitPoints,omitempty"`
+	// CompletionEvent is received by composite states without exit symbol
+	CompletionEvent string `json:"completionEvent,omitempty"`
+	// Profile holds the options of the machine (see DFA.SetProfile)
+	Profile *Profile `json:"profile,omitempty"`
+}
+
+// StateDefinition is the serialized form of a state.
+type StateDefinition struct {
+	Name  string `json:"name"`
+	Final bool   `json:"final,omitempty"`
+	// Transitions is structured map[Symbol]State
+	Transitions map[string]string `json:"transitions,omitempty"`
+	// Weights is structured map[Symbol]Weight
+	Weights map[string]float64 `json:"weights,omitempty"`
+	// Submachine names the machine embedded by a composite state, which is
+	// left via the transition of the Exit symbol (see State.SetSubmachine)
+	Submachine string `json:"submachine,omitempty"`
+	Exit       string `json:"exit,omitempty"`
+	// Entries holds the entry points used by the transitions by symbol (see
+	// State.SetEntry)
+	Entries map[string]string `json:"entries,omitempty"`
+	// Flags holds the feature flags gating the transitions by symbol (see
+	// State.SetFlag)
+	Flags map[string]string `json:"flags,omitempty"`
+	// Position and Routes are layout hints (see State.SetPosition and SetRoute)
+	Position *Point             `json:"position,omitempty"`
+	Routes   map[string][]Point `json:"routes,omitempty"`
+	// Guards and Assign hold expressions of the transitions by symbol (see
+	// State.SetGuardExpr and SetAssignment)
+	Guards map[string]string `json:"guards,omitempty"`
+	Assign map[string]string `json:"assign,omitempty"`
+	// Choice holds the ordered branches of a choice pseudo-state (see
+	// State.SetChoice)
+	Choice []string `json:"choice,omitempty"`
+	// Defer holds the symbols deferred by the state (see State.Defer)
+	Defer []string        `json:"defer,omitempty"`
+	Join  *JoinDefinition `json:"join,omitempty"`
+	// Timeout leaves the state after a duration (see State.SetTimeout)
+	Timeout *TimeoutDefinition `json:"timeout,omitempty"`
+}
+
+// TimeoutDefinition is the serialized form of a timeout, After is a
+// duration like "15m" measured on the named Calendar if it is set.
+type TimeoutDefinition struct {
+	After    string `json:"after"`
+	Symbol   string `json:"symbol"`
+	Calendar string `json:"calendar,omitempty"`
+}
+
+// JoinDefinition is the serialized form of a join (see State.SetJoin and
+// SetQuorum).
+type JoinDefinition struct {
+	Symbol   string   `json:"symbol"`
+	Expected []string `json:"expected"`
+	// Quorum is the number of expected symbols needed, all if it is 0
+	Quorum int `json:"quorum,omitempty"`
+}
+
+// Definition returns the serializable definition of the DFA, states are sorted by name.
+func (m *DFA) Definition() *Definition {
+	definition := &Definition{Name: m.Name, Start: m.Start, Alphabet: m.Alphabet,
+		EntryPoints: copyNames(m.entryPoints), ExitPoints: copyNames(m.exitPoints),
+		CompletionEvent: m.completionEvent, Profile: m.Profile()}
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		stateDefinition := StateDefinition{Name: name, Final: state.Final, Choice: state.Choice(),
+			Defer: state.Deferred()}
+		if len(state.Transitions) > 0 {
+			stateDefinition.Transitions = make(map[string]string, len(state.Transitions))
+			for symbol, to := range state.Transitions {
+				stateDefinition.Transitions[symbol] = to
+			}
+		}
+		for symbol, weight := range state.Weights {
+			if stateDefinition.Weights == nil {
+				stateDefinition.Weights = make(map[string]float64, len(state.Weights))
+			}
+			stateDefinition.Weights[symbol] = weight
+		}
+		if state.submachine != nil {
+			stateDefinition.Submachine = state.submachine.Name
+			stateDefinition.Exit = state.exit
+		}
+		stateDefinition.Entries = copyNames(state.entries)
+		stateDefinition.Flags = copyNames(state.flags)
+		if after, symbol, ok := state.Timeout(); ok {
+			stateDefinition.Timeout = &TimeoutDefinition{After: after.String(), Symbol: symbol,
+				Calendar: state.TimeoutCalendar()}
+		}
+		if symbol, expected, ok := state.Join(); ok {
+			stateDefinition.Join = &JoinDefinition{Symbol: symbol, Expected: expected}
+			if quorum := state.Quorum(); quorum < len(expected) {
+				stateDefinition.Join.Quorum = quorum
+			}
+		}
+		if position, ok := state.Position(); ok {
+			stateDefinition.Position = &position
+		}
+		for symbol, route := range state.routes {
+			if stateDefinition.Routes == nil {
+				stateDefinition.Routes = make(map[string][]Point, len(state.routes))
+			}
+			stateDefinition.Routes[symbol] = append([]Point{}, route...)
+		}
+		for symbol, source := range state.guardExprs {
+			if stateDefinition.Guards == nil {
+				stateDefinition.Guards = make(map[string]string, len(state.guardExprs))
+			}
+			stateDefinition.Guards[symbol] = source
+		}
+		for symbol, assignment := range state.assignments {
+			if stateDefinition.Assign == nil {
+				stateDefinition.Assign = make(map[string]string, len(state.assignments))
+			}
+			stateDefinition.Assign[symbol] = assignment.String()
+		}
+		definition.States = append(definition.States, stateDefinition)
+	}
+	return definition
+}
+
+// FromDefinition builds a DFA from its definition. Definitions referencing
+// submachines have to be loaded with a Registry.
+func FromDefinition(definition *Definition) (*DFA, error) {
+	return fromDefinition(definition, func(name string) *DFA { return nil })
+}
+
+// fromDefinition builds a DFA from its definition, resolving submachines
+// by name.
+func fromDefinition(definition *Definition, submachine func(name string) *DFA) (*DFA, error) {
+	m := NewDFA(definition.Name)
+	m.States = make(map[string]*State)
+	for _, stateDefinition := range definition.States {
+		if m.StateExists(stateDefinition.Name) {
+			return nil, fmt.Errorf("%s '%s'", errDuplicateState, stateDefinition.Name)
+		}
+		state := NewState(stateDefinition.Name)
+		state.SetFinal(stateDefinition.Final)
+		for symbol, to := range stateDefinition.Transitions {
+			state.Transitions[symbol] = to
+		}
+		for symbol, weight := range stateDefinition.Weights {
+			state.SetWeight(symbol, weight)
+		}
+		if stateDefinition.Position != nil {
+			state.SetPosition(stateDefinition.Position.X, stateDefinition.Position.Y)
+		}
+		for symbol, route := range stateDefinition.Routes {
+			state.SetRoute(symbol, route...)
+		}
+		state.SetChoice(stateDefinition.Choice...)
+		state.Defer(stateDefinition.Defer...)
+		if stateDefinition.Timeout != nil {
+			after, err := time.ParseDuration(stateDefinition.Timeout.After)
+			if err != nil {
+				return nil, fmt.Errorf("timeout of state '%s': %w", stateDefinition.Name, err)
+			}
+			state.SetCalendarTimeout(after, stateDefinition.Timeout.Symbol, stateDefinition.Timeout.Calendar)
+		}
+		if stateDefinition.Join != nil {
+			state.SetQuorum(stateDefinition.Join.Symbol, stateDefinition.Join.Quorum,
+				stateDefinition.Join.Expected...)
+		}
+		for symbol, entryPoint := range stateDefinition.Entries {
+			state.SetEntry(symbol, entryPoint)
+		}
+		for symbol, flag := range stateDefinition.Flags {
+			state.SetFlag(symbol, flag)
+		}
+		for symbol, source := range stateDefinition.Guards {
+			if err := state.SetGuardExpr(symbol, source); err != nil {
+				return nil, fmt.Errorf("guard '%s' of state '%s': %w", symbol, stateDefinition.Name, err)
+			}
+		}
+		for symbol, source := range stateDefinition.Assign {
+			if err := state.SetAssignment(symbol, source); err != nil {
+				return nil, fmt.Errorf("assignment '%s' of state '%s': %w", symbol, stateDefinition.Name, err)
+			}
+		}
+		if stateDefinition.Submachine != "" {
+			inner := submachine(stateDefinition.Submachine)
+			if inner == nil {
+				return nil, fmt.Errorf("%s '%s' of state '%s'", errUnknownMachine,
+					stateDefinition.Submachine, stateDefinition.Name)
+			}
+			state.SetSubmachine(inner, stateDefinition.Exit)
+		}
+		m.SetState(state)
+	}
+	m.SetStart(definition.Start)
+	if definition.Alphabet != nil {
+		m.SetAlphabet(definition.Alphabet)
+	}
+	m.SetCompletionEvent(definition.CompletionEvent)
+	for name, state := range definition.EntryPoints {
+		m.SetEntryPoint(name, state)
+	}
+	for state, name := range definition.ExitPoints {
+		m.SetExitPoint(state, name)
+	}
+	if err := m.SetProfile(definition.Profile); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Marshal serializes the DFA (states, transitions, start and final markers) to JSON.
+func Marshal(m *DFA) ([]byte, error) {
+	return json.MarshalIndent(m.Definition(), "", "  ")
+}
+
+// Unmarshal deserializes a DFA from JSON as produced by Marshal.
+func Unmarshal(data []byte) (*DFA, error) {
+	var definition Definition
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+	if definition.Name == "" && definition.States == nil {
+		return nil, errors.New(errEmptyDefinition)
+	}
+	return FromDefinition(&definition)
+}
+
+// ToDOT exports the DFA in the Graphviz DOT format. Final states are drawn
+// as double circles, the start state is marked by an incoming arrow.
+func (m *DFA) ToDOT() string {
+	return m.toDOT(false)
+}
+
+// ToDOTCollapsed exports the DFA like ToDOT, but draws all transitions
+// between two states as one edge labeled with the comma separated symbols.
+func (m *DFA) ToDOTCollapsed() string {
+	return m.toDOT(true)
+}
+
+// toDOT exports the DFA in the DOT format, optionally collapsing parallel
+// edges. Choice pseudo-states are drawn as diamonds.
+func (m *DFA) toDOT(collapse bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(m.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  __start [shape=point];\n")
+	for _, name := range sortedKeys(m.States) {
+		shape := "circle"
+		if m.States[name].Final {
+			shape = "doublecircle"
+		} else if m.States[name].choice != nil {
+			shape = "diamond"
+		}
+		fmt.Fprintf(&b, "  %s [shape=%s%s];\n", dotQuote(name), shape, dotPosition(m.States[name]))
+	}
+	if m.Start != "" {
+		fmt.Fprintf(&b, "  __start -> %s;\n", dotQuote(m.Start))
+	}
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		if collapse {
+			for _, to := range uniqueTargets(state) {
+				symbols := m.EdgesBetween(name, to)
+				var route []Point
+				for _, symbol := range symbols {
+					if route = state.Route(symbol); route != nil {
+						break
+					}
+				}
+				fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", dotQuote(name), dotQuote(to),
+					dotQuote(strings.Join(symbols, ", ")), dotRoute(route))
+			}
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", dotQuote(name),
+				dotQuote(state.Transitions[symbol]), dotQuote(symbol), dotRoute(state.Route(symbol)))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// copyNames copies a map of names, nil if it is empty.
+func copyNames(names map[string]string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(names))
+	for key, name := range names {
+		copied[key] = name
+	}
+	return copied
+}
+
+// dotQuote quotes an identifier for the DOT language.
+func dotQuote(id string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"`
+}
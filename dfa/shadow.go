@@ -0,0 +1,58 @@
+package dfa
+
+import (
+	"context"
+	"strings"
+)
+
+// Divergence records a token after which the shadow machine was in
+// another state than the primary one.
+type Divergence struct {
+	// Index is the position of the token since the shadow was attached
+	Index   int
+	Symbol  string
+	Primary []string
+	Shadow  []string
+}
+
+// SetShadow attaches a candidate version of the machine that receives the
+// same tokens in dry-run mode, so it has no side effects. After every token
+// the active states of both (see Active) are compared and divergences are
+// recorded. The shadow starts at the snapshot of the runner (see Snapshot);
+// if the machine can not restore it, e.g. because a state is missing, it
+// starts at the current state and diverges on the first token. nil
+// detaches the shadow.
+func (r *Runner) SetShadow(m *DFA) {
+	r.shadow = nil
+	r.fed = 0
+	r.divergences = nil
+	if m == nil {
+		return
+	}
+	shadow := NewRunner(m)
+	r.share(shadow)
+	shadow.spans, shadow.outbox = nil, nil
+	shadow.SetDryRun(true, nil)
+	if err := shadow.Restore(r.Snapshot()); err != nil {
+		shadow.current, shadow.vars = r.current, make(Vars)
+	}
+	r.shadow = shadow
+}
+
+// Divergences returns the divergences recorded since the shadow was attached.
+func (r *Runner) Divergences() []Divergence {
+	return append([]Divergence{}, r.divergences...)
+}
+
+// compare feeds the token to the shadow with the context the primary was
+// fed with and records a divergence.
+func (r *Runner) compare(ctx context.Context, token string) {
+	r.shadow.tick(r.shadow.context(ctx))
+	r.shadow.feed(r.shadow.context(ctx), token)
+	primary, shadow := r.Active(), r.shadow.Active()
+	if strings.Join(primary, "\x00") != strings.Join(shadow, "\x00") {
+		r.divergences = append(r.divergences, Divergence{Index: r.fed, Symbol: token,
+			Primary: primary, Shadow: shadow})
+	}
+	r.fed++
+}
@@ -0,0 +1,84 @@
+package dfa
+
+import "unsafe"
+
+// mapEntryOverhead is a rough estimate of the per-entry overhead of a Go map.
+const mapEntryOverhead = 8
+
+// MemStats holds estimated memory usage in bytes.
+type MemStats struct {
+	// States covers the state structures and their names
+	States int64
+	// Transitions covers the transition maps of all states
+	Transitions int64
+	// Indexes covers StateLookup, EdgeLookup and the bookkeeping to update
+	// them incrementally, or the lookup tables of a CompiledDFA
+	Indexes int64
+	// Total is the sum of all the above including the DFA itself
+	Total int64
+}
+
+// Size estimates the memory used by the states, transitions and indexes
+// of the DFA. The numbers are estimates based on the sizes of the
+// structures and strings involved and do not account for allocator slack.
+func (m *DFA) Size() MemStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var stats MemStats
+	stringSize := int64(unsafe.Sizeof(""))
+	pointerSize := int64(unsafe.Sizeof(m))
+	for name, state := range m.States {
+		stats.States += stringSize + int64(len(name)) + pointerSize + mapEntryOverhead
+		stats.States += int64(unsafe.Sizeof(*state)) + int64(len(state.Name))
+		for symbol, to := range state.Transitions {
+			stats.Transitions += 2*stringSize + int64(len(symbol)+len(to)) + mapEntryOverhead
+		}
+	}
+	sliceSize := int64(unsafe.Sizeof([]string{}))
+	for key, states := range m.StateLookup {
+		stats.Indexes += stringSize + int64(len(key)) + sliceSize + mapEntryOverhead
+		stats.Indexes += int64(cap(states)) * stringSize
+	}
+	for symbol, edges := range m.EdgeLookup {
+		stats.Indexes += stringSize + int64(len(symbol)) + sliceSize + mapEntryOverhead
+		stats.Indexes += int64(cap(edges)) * (pointerSize + int64(unsafe.Sizeof(Edge{})))
+	}
+	entrySize := int64(unsafe.Sizeof(indexEntry{}))
+	for name, entries := range m.contributions {
+		stats.Indexes += stringSize + int64(len(name)) + sliceSize + mapEntryOverhead
+		stats.Indexes += int64(cap(entries)) * entrySize
+	}
+	for name, sources := range m.incoming {
+		stats.Indexes += stringSize + int64(len(name)) + pointerSize + mapEntryOverhead
+		for source := range sources {
+			stats.Indexes += stringSize + int64(len(source)) + 1 + mapEntryOverhead
+		}
+	}
+	stats.Total = int64(unsafe.Sizeof(*m)) + int64(len(m.Name)+len(m.Start)) +
+		stats.States + stats.Transitions + stats.Indexes
+	return stats
+}
+
+// Size estimates the memory used by the compiled DFA like DFA.Size.
+func (c *CompiledDFA) Size() MemStats {
+	var stats MemStats
+	stringSize := int64(unsafe.Sizeof(""))
+	intSize := int64(unsafe.Sizeof(0))
+	for _, state := range c.states {
+		stats.States += int64(unsafe.Sizeof(state)) + int64(len(state.name))
+		for symbol := range state.transitions {
+			stats.Transitions += stringSize + int64(len(symbol)) + intSize + mapEntryOverhead
+		}
+		for _, symbol := range state.symbols {
+			stats.Transitions += stringSize + int64(len(symbol))
+		}
+	}
+	for name := range c.index {
+		stats.Indexes += stringSize + int64(len(name)) + intSize + mapEntryOverhead
+	}
+	for symbol := range c.known {
+		stats.Indexes += stringSize + int64(len(symbol)) + 1 + mapEntryOverhead
+	}
+	stats.Total = int64(unsafe.Sizeof(*c)) + int64(len(c.name)) + stats.States + stats.Transitions + stats.Indexes
+	return stats
+}
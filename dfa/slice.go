@@ -0,0 +1,66 @@
+package dfa
+
+// Slice extracts the sub-machine reachable from the given state within
+// depth steps. The state becomes the start state, transitions leaving the
+// extracted states are dropped.
+func (m *DFA) Slice(from string, depth int) *DFA {
+	distance := map[string]int{from: 0}
+	queue := []string{from}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		state := m.GetState(name)
+		if state == nil || distance[name] >= depth {
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			to := state.Transitions[symbol]
+			if _, ok := distance[to]; !ok {
+				distance[to] = distance[name] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	keep := make(map[string]bool, len(distance))
+	for name := range distance {
+		keep[name] = true
+	}
+	return m.subMachine(from, keep)
+}
+
+// subMachine returns a new DFA starting at the given state holding copies
+// of the kept states and the transitions between them.
+func (m *DFA) subMachine(start string, keep map[string]bool) *DFA {
+	sub := NewDFA(m.Name)
+	sub.SetNamer(m.namer)
+	sub.workers = m.workers
+	sub.SetStart(start)
+	for name := range keep {
+		state := m.GetState(name)
+		if state == nil {
+			continue
+		}
+		copied := state.copy()
+		for symbol, to := range copied.Transitions {
+			if !keep[to] {
+				delete(copied.Transitions, symbol)
+			}
+		}
+		sub.SetState(copied)
+	}
+	return sub
+}
+
+// ExtractBetween keeps only the states and transitions that lie on some
+// path from state a to state b, with a as the start state. The result has
+// no states if b can not be reached from a.
+func (m *DFA) ExtractBetween(a, b string) *DFA {
+	keep := make(map[string]bool)
+	reaching := m.reaching([]string{b})
+	for name := range m.reachableFrom(a) {
+		if reaching[name] {
+			keep[name] = true
+		}
+	}
+	return m.subMachine(a, keep)
+}
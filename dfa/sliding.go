@@ -0,0 +1,56 @@
+package dfa
+
+// Match describes an accepted slice tokens[Start:End] of the input.
+type Match struct {
+	Start int
+	End   int
+}
+
+// RunSliding anchors the DFA at every position of the tokens and reports
+// every non-empty slice of at most window tokens that drives the machine
+// from its start state into a final state.
+func (m *DFA) RunSliding(tokens []string, window int) []Match {
+	var matches []Match
+	for start := range tokens {
+		end := min(start+window, len(tokens))
+		for _, length := range m.acceptedLengths(tokens[start:end]) {
+			if length > 0 {
+				matches = append(matches, Match{Start: start, End: start + length})
+			}
+		}
+	}
+	return matches
+}
+
+// acceptedLengths returns every length l for which tokens[:l] drives the
+// machine from its start state into a final state.
+func (m *DFA) acceptedLengths(tokens []string) []int {
+	var lengths []int
+	current := m.GetState(m.Start)
+	if current == nil {
+		return lengths
+	}
+	if current.Final {
+		lengths = append(lengths, 0)
+	}
+	for i, token := range tokens {
+		next, ok := m.via(current, token)
+		if !ok {
+			break
+		}
+		if current = m.GetState(next); current == nil {
+			break
+		}
+		if current.Final {
+			lengths = append(lengths, i+1)
+		}
+	}
+	return lengths
+}
+
+// AcceptingPrefixes returns every index i at which the consumed prefix
+// tokens[:i] is accepted, instead of stopping at the first final state
+// like Run does. Index 0 is included if the start state is final.
+func (m *DFA) AcceptingPrefixes(tokens []string) []int {
+	return m.acceptedLengths(tokens)
+}
@@ -0,0 +1,66 @@
+package dfa
+
+import (
+	"context"
+	"strconv"
+)
+
+// SpanTracer creates spans for distributed tracing without depending on a
+// tracing library; adapters for OpenTelemetry or other stacks implement it
+// outside of this package.
+type SpanTracer interface {
+	// StartSpan starts a span as child of the span in the context and
+	// returns a context carrying the new span
+	StartSpan(ctx context.Context, name string, attributes map[string]string) context.Context
+	// EndSpan ends the span carried by the context, adding the attributes
+	EndSpan(ctx context.Context, attributes map[string]string, err error)
+}
+
+// spans holds the span tracer of a runner and the context of the span of
+// the token being fed, shared with the runners of submachines.
+type spans struct {
+	tracer SpanTracer
+	ctx    context.Context
+}
+
+// SetSpanTracer lets the runner create a span "gopher-state.feed" for every
+// token fed and a child span "gopher-state.transition" for every attempted
+// transition, including automatic ones, with the machine, states, symbols
+// and whether the transition was taken as attributes. Spans of FeedContext are children of the span in its context.
+// Passing nil removes the span tracer.
+func (r *Runner) SetSpanTracer(tracer SpanTracer) {
+	r.spans = nil
+	if tracer != nil {
+		r.spans = &spans{tracer: tracer, ctx: context.Background()}
+	}
+	for inner := r.inner; inner != nil; inner = inner.inner {
+		inner.spans = r.spans
+	}
+}
+
+// traceFeed feeds the token within a span.
+func (r *Runner) traceFeed(ctx context.Context, token string) (string, bool, error) {
+	parent := ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	r.spans.ctx = r.spans.tracer.StartSpan(parent, "gopher-state.feed", map[string]string{
+		"machine": r.machine.Name, "state": r.current, "symbol": token})
+	current, ok, err := r.feed(ctx, token)
+	r.spans.tracer.EndSpan(r.spans.ctx, map[string]string{"state": current, "taken": strconv.FormatBool(ok)}, err)
+	r.spans.ctx = context.Background()
+	return current, ok, err
+}
+
+// traceAdvance advances within a span.
+func (r *Runner) traceAdvance(ctx context.Context, symbol string) (string, bool, error) {
+	spanCtx := r.spans.tracer.StartSpan(r.spans.ctx, "gopher-state.transition", map[string]string{
+		"machine": r.machine.Name, "from": r.current, "symbol": symbol})
+	next, ok, err := r.advance(ctx, symbol)
+	attributes := map[string]string{"taken": strconv.FormatBool(ok)}
+	if ok {
+		attributes["to"] = next
+	}
+	r.spans.tracer.EndSpan(spanCtx, attributes, err)
+	return next, ok, err
+}
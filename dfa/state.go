@@ -1,12 +1,49 @@
 package dfa
 
+import (
+	"time"
+
+	"github.com/breskos/gopher-state/expr"
+)
+
 type State struct {
 	// Name represents the name of the state
 	Name string
 	// Transitions represents the transitions of the state.
 	// The map is structured map[Symbol]State
 	Transitions map[string]string
-	Final       bool
+	// Weights holds optional weights of the transitions by symbol
+	Weights map[string]float64
+	Final   bool
+	// guards holds the guards of the transitions by symbol
+	guards map[string]Guard
+	// guardExprs and assignments hold the expressions of the transitions
+	guardExprs  map[string]string
+	assignments map[string]*expr.Assignment
+	// actions holds the actions of the transitions by symbol
+	actions map[string]Action
+	// deferred holds the symbols deferred by the state
+	deferred map[string]bool
+	// join advances the state once it received the expected symbols
+	join *join
+	// timeout leaves the state after a duration
+	timeout *timeout
+	// choice holds the ordered branches of a choice pseudo-state
+	choice []string
+	// submachine is the embedded machine of a composite state, left via exit
+	submachine *DFA
+	exit       string
+	// entries holds the entry points used by the transitions by symbol
+	entries map[string]string
+	// flags holds the feature flags gating the transitions by symbol
+	flags map[string]string
+	// position and routes are layout hints of diagrams
+	position *Point
+	routes   map[string][]Point
+	// dwell is the expected time spent in the state
+	dwell time.Duration
+	// owner is the DFA the state was set to, notified about new transitions
+	owner *DFA
 }
 
 // NewState creates a new state
@@ -26,16 +63,19 @@ func (s *State) GetTransitions() map[string]string {
 // AddTransitions adds a bulk of symbols to the state that all end up in the same state
 func (s *State) AddTransitions(state *State, symbols []string) {
 	for _, symbol := range symbols {
-		s.Transitions[symbol] = state.Name
+		s.AddTransition(state, symbol)
 	}
 }
 
-// AddTransitions adds a symbol that leads to a state - meaning a transition
+// AddTransition adds a symbol that leads to a state - meaning a transition
 func (s *State) AddTransition(state *State, symbol string) {
 	if s.Transitions == nil {
 		s.Transitions = make(map[string]string)
 	}
 	s.Transitions[symbol] = state.Name
+	if s.owner != nil {
+		s.owner.stateChanged(s.Name)
+	}
 }
 
 // Via is used by the DFA to find a transition using a symbol
@@ -53,7 +93,79 @@ func (s *State) IsFinal() bool {
 	return s.Final
 }
 
+// RemoveTransition removes the transition of the symbol.
+func (s *State) RemoveTransition(symbol string) {
+	if _, ok := s.Transitions[symbol]; !ok {
+		return
+	}
+	delete(s.Transitions, symbol)
+	if s.owner != nil {
+		s.owner.stateChanged(s.Name)
+	}
+}
+
 // SetFinal sets this state to a final state
 func (s *State) SetFinal(final bool) {
 	s.Final = final
+	if s.owner != nil && s.owner.reachability != nil {
+		s.owner.reachability.update(s.Name)
+	}
+}
+
+// copy returns a deep copy of the state
+func (s *State) copy() *State {
+	c := NewState(s.Name)
+	c.Final = s.Final
+	for symbol, to := range s.Transitions {
+		c.Transitions[symbol] = to
+	}
+	for symbol, weight := range s.Weights {
+		c.SetWeight(symbol, weight)
+	}
+	for symbol, guard := range s.guards {
+		c.SetGuard(symbol, guard)
+	}
+	for symbol, source := range s.guardExprs {
+		if c.guardExprs == nil {
+			c.guardExprs = make(map[string]string)
+		}
+		c.guardExprs[symbol] = source
+	}
+	for symbol, assignment := range s.assignments {
+		if c.assignments == nil {
+			c.assignments = make(map[string]*expr.Assignment)
+		}
+		c.assignments[symbol] = assignment
+	}
+	for symbol, action := range s.actions {
+		if c.actions == nil {
+			c.actions = make(map[string]Action)
+		}
+		c.actions[symbol] = action
+	}
+	c.SetChoice(s.choice...)
+	if s.timeout != nil {
+		c.SetCalendarTimeout(s.timeout.after, s.timeout.symbol, s.timeout.calendar)
+	}
+	if s.join != nil {
+		c.SetJoin(s.join.symbol, s.join.expected...)
+	}
+	for symbol := range s.deferred {
+		c.Defer(symbol)
+	}
+	c.submachine, c.exit = s.submachine, s.exit
+	for symbol, entryPoint := range s.entries {
+		c.SetEntry(symbol, entryPoint)
+	}
+	for symbol, flag := range s.flags {
+		c.SetFlag(symbol, flag)
+	}
+	c.dwell = s.dwell
+	if s.position != nil {
+		c.SetPosition(s.position.X, s.position.Y)
+	}
+	for symbol, route := range s.routes {
+		c.SetRoute(symbol, route...)
+	}
+	return c
 }
@@ -0,0 +1,76 @@
+package dfa
+
+import "fmt"
+
+var (
+	// StuckRule reports non-final states without outgoing transitions,
+	// a runner entering them can never finish.
+	StuckRule = Rule{Name: "stuck", Check: checkStuck}
+	// OutsideAlphabetRule reports states that are only reachable via
+	// symbols absent from the declared alphabet. It needs a declared alphabet.
+	OutsideAlphabetRule = Rule{Name: "outside-alphabet", Check: checkOutsideAlphabet}
+	// FinalWithEdgesRule reports final states with outgoing transitions,
+	// which Run never takes because it returns at the first final state.
+	FinalWithEdgesRule = Rule{Name: "final-with-edges", Check: checkFinalWithEdges}
+)
+
+// StuckRules are the heuristics applied by DetectStuck.
+var StuckRules = []Rule{StuckRule, OutsideAlphabetRule, FinalWithEdgesRule}
+
+// DetectStuck reports states a run can get stuck in or that behave
+// differently than they suggest, it is Lint with the StuckRules.
+func DetectStuck(m *DFA) []Finding {
+	return Lint(m, StuckRules...)
+}
+
+func checkStuck(m *DFA) []Finding {
+	var findings []Finding
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		if !state.Final && len(state.Transitions) == 0 {
+			findings = append(findings, Finding{State: name, Message: "not final and without outgoing transitions"})
+		}
+	}
+	return findings
+}
+
+func checkOutsideAlphabet(m *DFA) []Finding {
+	if m.Alphabet == nil || !m.StateExists(m.Start) {
+		return nil
+	}
+	visited := map[string]bool{m.Start: true}
+	queue := []string{m.Start}
+	for len(queue) > 0 {
+		state := m.GetState(queue[0])
+		queue = queue[1:]
+		if state == nil {
+			continue
+		}
+		for symbol, to := range state.Transitions {
+			if (symbol == AnySymbol || contains(m.Alphabet, symbol)) && !visited[to] {
+				visited[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	var findings []Finding
+	reachable := m.reachableFrom(m.Start)
+	for _, name := range sortedKeys(m.States) {
+		if reachable[name] && !visited[name] {
+			findings = append(findings, Finding{State: name, Message: "only reachable via symbols outside the alphabet"})
+		}
+	}
+	return findings
+}
+
+func checkFinalWithEdges(m *DFA) []Finding {
+	var findings []Finding
+	for _, name := range sortedKeys(m.States) {
+		state := m.States[name]
+		if state.Final && len(state.Transitions) > 0 {
+			findings = append(findings, Finding{State: name,
+				Message: fmt.Sprintf("final with %d outgoing transitions that Run never takes", len(state.Transitions))})
+		}
+	}
+	return findings
+}
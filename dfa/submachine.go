@@ -0,0 +1,103 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+)
+
+const errNoExit = "no exit transition"
+
+// SetSubmachine makes the state a composite state embedding the machine.
+// When a Runner enters the state, tokens are delegated to the submachine
+// until it reaches a final state, then the runner leaves the state via its
+// transition for the exit symbol, or for the completion event of the machine
+// if the exit symbol is empty (see SetCompletionEvent). Submachines can be
+// nested. Only Runner executes submachines, Step and Run treat the state
+// like any other state.
+func (s *State) SetSubmachine(machine *DFA, exit string) {
+	s.submachine = machine
+	s.exit = exit
+}
+
+// Submachine returns the embedded machine and the exit symbol of the
+// state, the machine is nil if the state is not a composite state.
+func (s *State) Submachine() (*DFA, string) {
+	return s.submachine, s.exit
+}
+
+// Active returns the current states from the outer machine to the
+// innermost active submachine.
+func (r *Runner) Active() []string {
+	active := []string{r.current}
+	if r.inner != nil {
+		active = append(active, r.inner.Active()...)
+	}
+	return active
+}
+
+// descend starts the submachine of the current state if it is a composite
+// state and no submachine is active, at the entry point of the transition
+// that entered the state if it has one. A submachine that starts in a final
+// state or an exit point is left right away.
+func (r *Runner) descend(ctx context.Context) error {
+	state := r.machine.GetState(r.current)
+	if r.inner != nil || state == nil || state.submachine == nil {
+		return nil
+	}
+	inner, err := enter(state.submachine, r.entry)
+	r.entry = ""
+	if err != nil {
+		return err
+	}
+	r.inner = inner
+	r.share(r.inner)
+	r.inner.occupy(r.inner.current)
+	r.inner.entered = r.clock.Now()
+	if err := r.inner.descend(ctx); err != nil {
+		return err
+	}
+	return r.ascend(ctx)
+}
+
+// share passes the settings shared with submachines on to the runner.
+func (r *Runner) share(inner *Runner) {
+	inner.vars = r.vars
+	inner.clock = r.clock
+	inner.spans = r.spans
+	inner.dryRun, inner.record = r.dryRun, r.record
+	inner.flags = r.flags
+	inner.calendars = r.calendars
+	inner.outbox = r.outbox
+}
+
+// ascend leaves the composite state via its exit transition once the
+// active submachine is in a final state, or via the transition named by the
+// exit point the submachine reached. The exit transition is taken with the
+// context like any other.
+func (r *Runner) ascend(ctx context.Context) error {
+	if r.inner == nil {
+		return nil
+	}
+	composite := r.current
+	_, exit := r.machine.GetState(composite).Submachine()
+	if exit == "" {
+		exit = r.machine.completionEvent
+	}
+	exit, ok := r.inner.done(exit)
+	if !ok {
+		return nil
+	}
+	r.inner.release()
+	r.inner = nil
+	ok, err := r.step(ctx, exit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s '%s' of state '%s'", errNoExit, exit, composite)
+	}
+	if err := r.route(ctx); err != nil {
+		return err
+	}
+	return r.descend(ctx)
+}
@@ -0,0 +1,72 @@
+package dfa
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	errUnresolvedVariable = "unresolved variable"
+	errUnterminated       = "unterminated variable"
+	defaultSeparator      = ":-"
+)
+
+// ExpandTemplate resolves the variables of a JSON definition before it is
+// loaded, so one definition can serve several environments. A variable is
+// written as ${NAME} or ${NAME:-default} and is replaced by the value
+// lookup returns for NAME, or the default if there is none. Inside JSON
+// strings the value is escaped, outside it is inserted as is, so variables
+// can also provide numbers, e.g. "weights": {"retry": ${RETRY_WEIGHT:-1}}.
+func ExpandTemplate(data []byte, lookup func(name string) (string, bool)) ([]byte, error) {
+	var b strings.Builder
+	text := string(data)
+	inString := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '$' && i+1 < len(text) && text[i+1] == '{' {
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%s at position %d", errUnterminated, i)
+			}
+			name := text[i+2 : i+end]
+			value, ok := "", false
+			if at := strings.Index(name, defaultSeparator); at >= 0 {
+				name, value, ok = name[:at], name[at+len(defaultSeparator):], true
+			}
+			if resolved, found := lookup(name); found {
+				value, ok = resolved, true
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s '%s'", errUnresolvedVariable, name)
+			}
+			if inString {
+				quoted := strconv.Quote(value)
+				value = quoted[1 : len(quoted)-1]
+			}
+			b.WriteString(value)
+			i += end
+			continue
+		}
+		if c == '"' {
+			inString = !inString
+		} else if c == '\\' && inString && i+1 < len(text) {
+			b.WriteByte(c)
+			i++
+			c = text[i]
+		}
+		b.WriteByte(c)
+	}
+	return []byte(b.String()), nil
+}
+
+// UnmarshalEnv deserializes a DFA from JSON like Unmarshal after resolving
+// its variables from the environment (see ExpandTemplate).
+func UnmarshalEnv(data []byte) (*DFA, error) {
+	expanded, err := ExpandTemplate(data, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(expanded)
+}
@@ -0,0 +1,120 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const errNoTimeoutTransition = "no timeout transition"
+
+// timeout leaves a state via the transition of symbol after a duration,
+// measured on the named calendar if it is set.
+type timeout struct {
+	after    time.Duration
+	symbol   string
+	calendar string
+}
+
+// SetTimeout lets a Runner take the transition of the symbol once it stayed
+// in the state for the duration, measured with the clock of the runner
+// (see Runner.SetClock). This correlates symbols in time windows, e.g. a
+// state entered by "authorized" with the transition "captured" and a
+// timeout of 15 minutes via "capture_timeout" detects payments that were
+// not captured in time. Timeouts expire before the next token is fed or
+// when the runner is ticked (see Runner.Tick). A duration of 0 removes the
+// timeout.
+func (s *State) SetTimeout(after time.Duration, symbol string) {
+	if after <= 0 {
+		s.timeout = nil
+		return
+	}
+	s.timeout = &timeout{after: after, symbol: symbol}
+}
+
+// Timeout returns the duration and symbol of the timeout of the state, ok
+// is false if the state has none.
+func (s *State) Timeout() (after time.Duration, symbol string, ok bool) {
+	if s.timeout == nil {
+		return 0, "", false
+	}
+	return s.timeout.after, s.timeout.symbol, true
+}
+
+// SetClock sets the clock of the runner and its active submachines. The
+// current states count as entered at the time of the clock.
+func (r *Runner) SetClock(clock Clock) {
+	for level := r; level != nil; level = level.inner {
+		level.clock = clock
+		level.entered = clock.Now()
+	}
+	if r.shadow != nil {
+		r.shadow.SetClock(clock)
+	}
+}
+
+// Entered returns the time the runner entered its current state.
+func (r *Runner) Entered() time.Time {
+	return r.entered
+}
+
+// Tick expires the timeouts of the current states, innermost first, and
+// returns whether a timeout transition was taken. A state entered by a
+// timeout counts as entered at the moment the timeout expired, so chained
+// timeouts expire as they would have in real time. Guards and flags are
+// evaluated with a context carrying the variables and the history of the
+// runner.
+func (r *Runner) Tick() (bool, error) {
+	return r.tick(r.context(context.Background()))
+}
+
+// tick expires the timeouts like Tick, evaluating guards and flags with the
+// context.
+func (r *Runner) tick(ctx context.Context) (bool, error) {
+	fired := false
+	if r.inner != nil {
+		ok, err := r.inner.tick(ctx)
+		if err != nil {
+			return fired, err
+		}
+		if ok {
+			fired = true
+			if err := r.ascend(ctx); err != nil {
+				return fired, err
+			}
+		}
+	}
+	// timeouts leading into each other in a cycle expire at most once per state
+	for visited := 0; visited <= len(r.machine.States); visited++ {
+		state := r.machine.GetState(r.current)
+		if state == nil || state.timeout == nil {
+			return fired, nil
+		}
+		deadline, err := r.deadline(state.timeout)
+		if err != nil {
+			return fired, err
+		}
+		if r.clock.Now().Before(deadline) {
+			return fired, nil
+		}
+		from := r.current
+		r.inner.release()
+		r.inner = nil
+		ok, err := r.step(ctx, state.timeout.symbol)
+		if err != nil {
+			return fired, err
+		}
+		if !ok {
+			return fired, fmt.Errorf("%s '%s' of state '%s'", errNoTimeoutTransition, state.timeout.symbol, from)
+		}
+		r.entered = deadline
+		fired = true
+		if err := r.route(ctx); err != nil {
+			return fired, err
+		}
+		if err := r.descend(ctx); err != nil {
+			return fired, err
+		}
+	}
+	return fired, nil
+}
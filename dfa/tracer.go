@@ -0,0 +1,160 @@
+package dfa
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"sync"
+)
+
+// Tracer observes the execution of a DFA or Runner.
+type Tracer interface {
+	// OnStep is called for every transition taken
+	OnStep(from, symbol, to string)
+	// OnReject is called when a symbol has no transition in the state
+	OnReject(state, symbol string)
+	// OnAccept is called with the path when the input was accepted
+	OnAccept(path []string)
+}
+
+// SetTracer attaches a tracer to Step, StepContext and Run. Passing nil
+// removes the tracer.
+func (m *DFA) SetTracer(tracer Tracer) {
+	m.tracer = tracer
+}
+
+// reject reports a rejected symbol to the tracer.
+func (m *DFA) reject(state, symbol string) {
+	if m.tracer != nil {
+		m.tracer.OnReject(state, symbol)
+	}
+}
+
+// Hit identifies a transition counted by the CountingTracer.
+type Hit struct {
+	From   string
+	Symbol string
+	To     string
+}
+
+// CountingTracer is a Tracer that counts steps, rejections, acceptances
+// and how often every transition was taken. It is safe for concurrent use.
+type CountingTracer struct {
+	mu      sync.Mutex
+	steps   int64
+	rejects int64
+	accepts int64
+	hits    map[Hit]int64
+	metrics *expvar.Map
+}
+
+// NewCountingTracer creates a new tracer with all counters at zero.
+func NewCountingTracer() *CountingTracer {
+	return &CountingTracer{hits: make(map[Hit]int64)}
+}
+
+// OnStep counts the step and the transition.
+func (t *CountingTracer) OnStep(from, symbol, to string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps++
+	t.hits[Hit{From: from, Symbol: symbol, To: to}]++
+	if t.metrics != nil {
+		t.metrics.Add("steps", 1)
+		t.metrics.Add(from+" -"+symbol+"-> "+to, 1)
+	}
+}
+
+// OnReject counts the rejection.
+func (t *CountingTracer) OnReject(state, symbol string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rejects++
+	if t.metrics != nil {
+		t.metrics.Add("rejects", 1)
+	}
+}
+
+// OnAccept counts the acceptance.
+func (t *CountingTracer) OnAccept(path []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.accepts++
+	if t.metrics != nil {
+		t.metrics.Add("accepts", 1)
+	}
+}
+
+// Steps returns the number of transitions taken.
+func (t *CountingTracer) Steps() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.steps
+}
+
+// Rejects returns the number of rejected symbols.
+func (t *CountingTracer) Rejects() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rejects
+}
+
+// Accepts returns the number of accepted inputs.
+func (t *CountingTracer) Accepts() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accepts
+}
+
+// Hits returns a copy of the number of times every transition was taken.
+func (t *CountingTracer) Hits() map[Hit]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hits := make(map[Hit]int64, len(t.hits))
+	for hit, count := range t.hits {
+		hits[hit] = count
+	}
+	return hits
+}
+
+// Publish exposes the counters via expvar under "gopher-state.<name>.trace"
+// from now on: "steps", "rejects", "accepts" and one counter per transition
+// named "from -symbol-> to".
+func (t *CountingTracer) Publish(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	name = expvarPrefix + name + ".trace"
+	if existing, ok := expvar.Get(name).(*expvar.Map); ok {
+		t.metrics = existing
+		return
+	}
+	t.metrics = expvar.NewMap(name)
+}
+
+// LogTracer logs the steps, rejections and acceptances of a machine.
+type LogTracer struct {
+	logger  *slog.Logger
+	level   slog.Level
+	machine string
+}
+
+// NewLogTracer creates a tracer logging to the logger at the level, the
+// records carry the machine name.
+func NewLogTracer(logger *slog.Logger, level slog.Level, machine string) *LogTracer {
+	return &LogTracer{logger: logger, level: level, machine: machine}
+}
+
+// OnStep logs the transition.
+func (t *LogTracer) OnStep(from, symbol, to string) {
+	t.logger.Log(context.Background(), t.level, "step", "machine", t.machine, "from", from, "symbol", symbol, "to", to)
+}
+
+// OnReject logs the rejected symbol.
+func (t *LogTracer) OnReject(state, symbol string) {
+	t.logger.Log(context.Background(), t.level, "reject", "machine", t.machine, "state", state, "symbol", symbol)
+}
+
+// OnAccept logs the accepted path.
+func (t *LogTracer) OnAccept(path []string) {
+	t.logger.Log(context.Background(), t.level, "accept", "machine", t.machine, "path", path)
+}
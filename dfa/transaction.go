@@ -0,0 +1,70 @@
+package dfa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkpoint is the state of a runner to roll back to.
+type checkpoint struct {
+	snapshot RunnerSnapshot
+	history  *History
+	steps    int
+	// shadow, fed and divergences record the shadow runner
+	shadow      *checkpoint
+	fed         int
+	divergences int
+}
+
+// checkpoint records the state of the runner, its history and its shadow.
+func (r *Runner) checkpoint() checkpoint {
+	c := checkpoint{snapshot: r.Snapshot(), steps: r.steps, fed: r.fed, divergences: len(r.divergences)}
+	if r.history != nil {
+		c.history = r.history.copy(r.history.now)
+	}
+	if r.shadow != nil {
+		shadow := r.shadow.checkpoint()
+		c.shadow = &shadow
+	}
+	return c
+}
+
+// rollback positions the runner at the checkpoint again. An error is only
+// returned if the machine changed since, so the snapshot can not be
+// restored.
+func (r *Runner) rollback(c checkpoint) error {
+	if err := r.Restore(c.snapshot); err != nil {
+		return err
+	}
+	r.steps = c.steps
+	if c.history != nil && r.history != nil {
+		r.history.rings = c.history.rings
+	}
+	if c.shadow != nil && r.shadow != nil {
+		// the shadow diverges on the next token if it can not be rolled back
+		r.shadow.rollback(*c.shadow)
+	}
+	r.fed, r.divergences = c.fed, r.divergences[:c.divergences]
+	return nil
+}
+
+// FeedAll feeds the tokens as one batch. If a token does not lead to a
+// transition, e.g. because it is rejected or deferred, or feeding it fails,
+// the runner is rolled back to its state before the batch, including its
+// variables, history and shadow, and the error wraps ErrInvalid or the
+// failure. Hooks and actions that were executed and intents recorded in
+// the outbox are not undone.
+func (r *Runner) FeedAll(tokens []string) error {
+	c := r.checkpoint()
+	for _, token := range tokens {
+		state := r.current
+		_, ok, err := r.Feed(token)
+		if err == nil && !ok {
+			err = fmt.Errorf("%w: '%s' in state '%s'", ErrInvalid, token, state)
+		}
+		if err != nil {
+			return errors.Join(err, r.rollback(c))
+		}
+	}
+	return nil
+}
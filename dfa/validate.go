@@ -0,0 +1,135 @@
+package dfa
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProblemKind classifies a structural problem of a DFA.
+type ProblemKind int
+
+const (
+	// MissingStart reports a start state that is not set or does not exist
+	MissingStart ProblemKind = iota
+	// NoFinalStates reports a DFA without any final state
+	NoFinalStates
+	// DanglingTransition reports a transition into a state that does not exist
+	DanglingTransition
+	// UnreachableState reports a state that can not be reached from the start
+	UnreachableState
+	// DeadState reports a state from which no final state can be reached
+	DeadState
+	// NamingViolation reports a state name or symbol violating the naming
+	// policy of the DFA
+	NamingViolation
+)
+
+// Problem is a structural issue found by Validate.
+type Problem struct {
+	Kind    ProblemKind
+	State   string
+	Symbol  string
+	Message string
+}
+
+// String returns the message of the problem.
+func (p Problem) String() string {
+	return p.Message
+}
+
+// Validate reports structural issues of the DFA: a missing start state,
+// the lack of final states, transitions into states that do not exist,
+// states that are unreachable from the start and states from which no
+// final state can be reached, as well as names violating the naming policy
+// (see SetNamingPolicy). Problems are ordered by kind and state.
+func (m *DFA) Validate() []Problem {
+	var problems []Problem
+	if !m.StateExists(m.Start) {
+		problems = append(problems, Problem{Kind: MissingStart, State: m.Start,
+			Message: fmt.Sprintf("start state '%s' does not exist", m.Start)})
+	}
+	names := sortedKeys(m.States)
+	hasFinal := false
+	for _, name := range names {
+		hasFinal = hasFinal || m.States[name].Final
+	}
+	if !hasFinal {
+		problems = append(problems, Problem{Kind: NoFinalStates, Message: "no final state is set"})
+	}
+	for _, name := range names {
+		state := m.States[name]
+		for _, symbol := range sortedSymbols(state) {
+			if to := state.Transitions[symbol]; !m.StateExists(to) {
+				problems = append(problems, Problem{Kind: DanglingTransition, State: name, Symbol: symbol,
+					Message: fmt.Sprintf("state '%s': symbol '%s' leads to missing state '%s'", name, symbol, to)})
+			}
+		}
+	}
+	for _, name := range m.unreachableStates() {
+		problems = append(problems, Problem{Kind: UnreachableState, State: name,
+			Message: fmt.Sprintf("state '%s' is not reachable from the start state", name)})
+	}
+	for _, name := range m.deadStates() {
+		problems = append(problems, Problem{Kind: DeadState, State: name,
+			Message: fmt.Sprintf("state '%s' can not reach a final state", name)})
+	}
+	if m.namingPolicy != nil {
+		for _, problem := range m.namingPolicy.violations(m) {
+			if problem.Symbol != "" {
+				problem.Message = fmt.Sprintf("state '%s': %s", problem.State, problem.Message)
+			}
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Reachable returns the sorted names of all existing states that can be
+// reached from the given state, including the state itself.
+func (m *DFA) Reachable(from string) []string {
+	var states []string
+	for name := range m.reachableFrom(from) {
+		if m.StateExists(name) {
+			states = append(states, name)
+		}
+	}
+	sort.Strings(states)
+	return states
+}
+
+// CanReachFinal tests if a final state can be reached from the state.
+func (m *DFA) CanReachFinal(state string) bool {
+	return m.StateExists(state) && m.coReachable()[state]
+}
+
+// unreachableStates returns the sorted states that can not be reached from
+// the start state, none if the start state does not exist.
+func (m *DFA) unreachableStates() []string {
+	if !m.StateExists(m.Start) {
+		return nil
+	}
+	var states []string
+	reachable := m.reachableFrom(m.Start)
+	for _, name := range sortedKeys(m.States) {
+		if !reachable[name] {
+			states = append(states, name)
+		}
+	}
+	return states
+}
+
+// deadStates returns the sorted states from which no final state can be
+// reached, none if the DFA has no final state.
+func (m *DFA) deadStates() []string {
+	coReachable := m.coReachable()
+	if len(coReachable) == 0 {
+		return nil
+	}
+	var states []string
+	for _, name := range sortedKeys(m.States) {
+		if !coReachable[name] {
+			states = append(states, name)
+		}
+	}
+	return states
+}
@@ -0,0 +1,245 @@
+package dfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const errInvalidSnapshot = "invalid snapshot"
+
+// Vars holds the variables of a Runner instance. Actions and hooks get
+// them via Context.Vars, guards via VarsFrom.
+type Vars map[string]any
+
+// Int returns the variable as int, 0 if it is not set or not a number.
+// Numbers restored from JSON snapshots are float64 and converted.
+func (v Vars) Int(name string) int {
+	switch value := v[name].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+// clone returns a copy of the variables.
+func (v Vars) clone() Vars {
+	copied := make(Vars, len(v))
+	for name, value := range v {
+		copied[name] = value
+	}
+	return copied
+}
+
+// reset replaces the variables by the saved ones in place, so runners
+// sharing them see the change.
+func (v Vars) reset(saved Vars) {
+	clear(v)
+	for name, value := range saved {
+		v[name] = value
+	}
+}
+
+// Add adds delta to an int variable, e.g. to count attempts.
+func (v Vars) Add(name string, delta int) int {
+	v[name] = v.Int(name) + delta
+	return v[name].(int)
+}
+
+// varsKey is the context key of the variables.
+type varsKey struct{}
+
+// WithVars returns a context carrying the variables.
+func WithVars(ctx context.Context, vars Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// VarsFrom returns the variables carried by the context, nil if none.
+func VarsFrom(ctx context.Context) Vars {
+	vars, _ := ctx.Value(varsKey{}).(Vars)
+	return vars
+}
+
+// Vars returns the variables of the runner, they can be changed directly.
+func (r *Runner) Vars() Vars {
+	return r.vars
+}
+
+// FeedContext processes one token like Feed, but evaluates the guards of
+// the transitions with the given context, extended by the variables and the
+// history of the runner.
+// Errors wrap ErrForbidden if a guard denied the transition and are a
+// *HookError if a hook failed it (see Context.Fail), the runner stays in its
+// state then.
+func (r *Runner) FeedContext(ctx context.Context, token string) (string, bool, error) {
+	return r.process(r.context(ctx), token)
+}
+
+// advance resolves the symbol in the current state, checks its feature
+// flag, evaluates the guard if a context is given, applies the assignments
+// and takes the transition; in dry-run mode the transition is only recorded.
+func (r *Runner) advance(ctx context.Context, symbol string) (string, bool, error) {
+	m := r.machine
+	current := m.GetState(r.current)
+	if current == nil {
+		return "", false, errors.New(errStateNotExistent)
+	}
+	transition, to, ok := m.resolve(current, symbol)
+	if !ok || r.flagged(ctx, current, transition) {
+		m.reject(r.current, symbol)
+		return "", false, nil
+	}
+	if ctx != nil {
+		if err := m.guard(ctx, current, transition, symbol); err != nil {
+			return "", false, err
+		}
+	}
+	r.entry = current.entries[transition]
+	if r.dryRun {
+		if r.record != nil {
+			r.record(Context{Machine: m, From: r.current, Symbol: symbol, To: to, Vars: r.vars})
+		}
+		return to, true, nil
+	}
+	// hooks may fail the transition, the variables are restored then
+	var saved Vars
+	if m.hooked(current, transition, to) {
+		saved = r.vars.clone()
+	}
+	if assignment, ok := current.assignments[transition]; ok {
+		if err := assignment.Apply(r.vars); err != nil {
+			m.reject(r.current, symbol)
+			return "", false, err
+		}
+	}
+	if r.outbox == nil || current.actions[transition] == nil {
+		if _, err := m.take(current, transition, symbol, to, r.vars); err != nil {
+			r.vars.reset(saved)
+			return "", false, err
+		}
+		return to, true, nil
+	}
+	id, err := r.outbox.Add(Intent{Machine: m.Name, From: r.current, Transition: transition, Symbol: symbol,
+		To: to, Vars: r.vars.clone()})
+	if err != nil {
+		return "", false, err
+	}
+	_, failed := m.take(current, transition, symbol, to, r.vars)
+	// a failed action is not redelivered, the transition is reverted
+	done := r.outbox.Done(id)
+	if failed != nil {
+		r.vars.reset(saved)
+		return "", false, errors.Join(failed, done)
+	}
+	return to, true, done
+}
+
+// RunnerSnapshot is the persistable state of a Runner.
+type RunnerSnapshot struct {
+	// Active holds the current states from the outer machine to the
+	// innermost active submachine
+	Active []string `json:"active"`
+	Vars   Vars     `json:"vars,omitempty"`
+	// Joined holds the symbols received by joins for each active state
+	Joined [][]string `json:"joined,omitempty"`
+	// Entered holds the times the active states were entered
+	Entered []time.Time `json:"entered,omitempty"`
+	// Deferred holds the tokens deferred for each active state
+	Deferred [][]string `json:"deferred,omitempty"`
+	// Seen holds the IDs of the recent events, oldest first (see SetDedup)
+	Seen []string `json:"seen,omitempty"`
+}
+
+// Snapshot returns the state of the runner including its variables, the
+// symbols received by joins, the deferred tokens, the times the states
+// were entered and the IDs of the recent events.
+func (r *Runner) Snapshot() RunnerSnapshot {
+	snapshot := RunnerSnapshot{Active: r.Active(), Vars: r.vars.clone(), Seen: r.dedup.recent()}
+	var joined, deferred [][]string
+	received, queued := false, false
+	for level := r; level != nil; level = level.inner {
+		snapshot.Entered = append(snapshot.Entered, level.entered)
+		joined = append(joined, level.Joined())
+		deferred = append(deferred, level.Deferred())
+		received = received || len(level.joined) > 0
+		queued = queued || len(level.deferred) > 0
+	}
+	if received {
+		snapshot.Joined = joined
+	}
+	if queued {
+		snapshot.Deferred = deferred
+	}
+	return snapshot
+}
+
+// Restore positions the runner as recorded by the snapshot. The states
+// have to exist, all but the innermost have to be composite states. Tokens
+// deferred or pending before are dropped. The IDs of the recent events are
+// only kept if deduplication is enabled (see SetDedup).
+func (r *Runner) Restore(snapshot RunnerSnapshot) error {
+	if len(snapshot.Active) == 0 || (snapshot.Joined != nil && len(snapshot.Joined) != len(snapshot.Active)) ||
+		(snapshot.Entered != nil && len(snapshot.Entered) != len(snapshot.Active)) ||
+		(snapshot.Deferred != nil && len(snapshot.Deferred) != len(snapshot.Active)) {
+		return errors.New(errInvalidSnapshot)
+	}
+	vars := snapshot.Vars.clone()
+	restored := &Runner{machine: r.machine}
+	r.share(restored)
+	if err := restored.restore(snapshot.Active, vars); err != nil {
+		return err
+	}
+	i := 0
+	for level := restored; level != nil; level = level.inner {
+		level.entered = r.clock.Now()
+		if snapshot.Entered != nil {
+			level.entered = snapshot.Entered[i]
+		}
+		if snapshot.Joined != nil {
+			for _, symbol := range snapshot.Joined[i] {
+				if level.joined == nil {
+					level.joined = make(map[string]bool)
+				}
+				level.joined[symbol] = true
+			}
+		}
+		if snapshot.Deferred != nil && len(snapshot.Deferred[i]) > 0 {
+			level.deferred = append([]string{}, snapshot.Deferred[i]...)
+		}
+		i++
+	}
+	r.inner.release()
+	r.current, r.inner, r.vars, r.joined = restored.current, restored.inner, vars, restored.joined
+	r.occupy(r.current)
+	for level := r.inner; level != nil; level = level.inner {
+		level.occupy(level.current)
+	}
+	r.entered, r.deferred = restored.entered, restored.deferred
+	r.entry, r.pending, r.steps = "", nil, 0
+	r.dedup.reset(snapshot.Seen)
+	r.path = []string{r.current}
+	return nil
+}
+
+// restore positions the runner and its submachines at the active states.
+func (r *Runner) restore(active []string, vars Vars) error {
+	state := r.machine.GetState(active[0])
+	if state == nil {
+		return fmt.Errorf("%s: %s '%s'", errInvalidSnapshot, errStateNotExistent, active[0])
+	}
+	r.current, r.vars = active[0], vars
+	if len(active) == 1 {
+		return nil
+	}
+	if state.submachine == nil {
+		return fmt.Errorf("%s: state '%s' has no submachine", errInvalidSnapshot, active[0])
+	}
+	r.inner = &Runner{machine: state.submachine}
+	r.share(r.inner)
+	return r.inner.restore(active[1:], vars)
+}
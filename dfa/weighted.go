@@ -0,0 +1,130 @@
+package dfa
+
+import (
+	"container/heap"
+	"math"
+)
+
+// AddWeightedTransition adds a transition like AddTransition with a weight,
+// typically the probability of the transition.
+func (s *State) AddWeightedTransition(state *State, symbol string, weight float64) {
+	s.AddTransition(state, symbol)
+	s.SetWeight(symbol, weight)
+}
+
+// SetWeight sets the weight of the transition of the symbol.
+func (s *State) SetWeight(symbol string, weight float64) {
+	if s.Weights == nil {
+		s.Weights = make(map[string]float64)
+	}
+	s.Weights[symbol] = weight
+}
+
+// Weight returns the weight of the transition of the symbol, 1 if unset.
+func (s *State) Weight(symbol string) float64 {
+	if weight, ok := s.Weights[symbol]; ok {
+		return weight
+	}
+	return 1
+}
+
+// RunWeighted runs all tokens from the start state and returns the states
+// visited (including the start state) and the product of the weights of
+// the transitions taken. ok reports whether every token could be consumed.
+func (m *DFA) RunWeighted(tokens []string) ([]string, float64, bool) {
+	current := m.GetState(m.Start)
+	if current == nil {
+		return nil, 0, false
+	}
+	path := []string{current.Name}
+	score := 1.0
+	for _, token := range tokens {
+		transition, to, ok := m.resolve(current, token)
+		if !ok {
+			return path, score, false
+		}
+		if transition != "" {
+			score *= current.Weight(transition)
+		}
+		path = append(path, to)
+		if current = m.GetState(to); current == nil {
+			return path, score, false
+		}
+	}
+	return path, score, true
+}
+
+// MostLikelyPath returns the path from one state to another that has the
+// highest product of weights, as states and the symbols between them.
+// Weights are treated as probabilities and have to be within (0, 1].
+func (m *DFA) MostLikelyPath(from, to string) ([]string, []string, float64, bool) {
+	type step struct{ previous, symbol string }
+	cost := map[string]float64{from: 0}
+	via := make(map[string]step)
+	done := make(map[string]bool)
+	queue := &costQueue{{name: from}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(costItem)
+		if done[item.name] {
+			continue
+		}
+		done[item.name] = true
+		if item.name == to {
+			break
+		}
+		state := m.GetState(item.name)
+		if state == nil {
+			continue
+		}
+		for _, symbol := range sortedSymbols(state) {
+			weight := state.Weight(symbol)
+			if weight <= 0 {
+				continue
+			}
+			next := state.Transitions[symbol]
+			c := item.cost - math.Log(weight)
+			if known, ok := cost[next]; !ok || c < known {
+				cost[next] = c
+				via[next] = step{previous: item.name, symbol: symbol}
+				heap.Push(queue, costItem{name: next, cost: c})
+			}
+		}
+	}
+	if !done[to] {
+		return nil, nil, 0, false
+	}
+	path := []string{to}
+	var symbols []string
+	for current := to; current != from; {
+		s := via[current]
+		path = append([]string{s.previous}, path...)
+		symbols = append([]string{s.symbol}, symbols...)
+		current = s.previous
+	}
+	return path, symbols, math.Exp(-cost[to]), true
+}
+
+// costItem is an entry of the priority queue of MostLikelyPath.
+type costItem struct {
+	name string
+	cost float64
+}
+
+// costQueue is a min-heap of costItems.
+type costQueue []costItem
+
+func (q costQueue) Len() int { return len(q) }
+func (q costQueue) Less(i, j int) bool {
+	if q[i].cost == q[j].cost {
+		return q[i].name < q[j].name
+	}
+	return q[i].cost < q[j].cost
+}
+func (q costQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *costQueue) Push(x interface{}) { *q = append(*q, x.(costItem)) }
+func (q *costQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,55 @@
+package dfa
+
+// AnySymbol is the symbol of a wildcard transition. It is taken for every
+// symbol the state has no other transition for. Analyses and constructions
+// over the language of a DFA treat it like any other symbol.
+const AnySymbol = "*"
+
+// UnknownPolicy determines how a DFA handles symbols that are not part of
+// its alphabet (see GetAlphabet) and have no wildcard transition.
+type UnknownPolicy int
+
+const (
+	// RejectUnknown rejects unknown symbols like any missing transition
+	RejectUnknown UnknownPolicy = iota
+	// IgnoreUnknown keeps the current state (self-loop) on unknown symbols
+	IgnoreUnknown
+	// SinkUnknown routes unknown symbols into the configured sink state
+	SinkUnknown
+)
+
+// SetDefault sets the default transition of the state, taken for every
+// symbol without a transition of its own.
+func (s *State) SetDefault(state *State) {
+	s.AddTransition(state, AnySymbol)
+}
+
+// SetUnknownPolicy sets the policy for unknown symbols. The sink state is
+// only used by SinkUnknown.
+func (m *DFA) SetUnknownPolicy(policy UnknownPolicy, sink string) {
+	m.unknownPolicy = policy
+	m.sink = sink
+}
+
+// unknown applies the unknown symbol policy to a symbol without a transition.
+func (m *DFA) unknown(state *State, symbol string) (string, string, bool) {
+	if m.unknownPolicy == RejectUnknown || m.knownSymbol(symbol) {
+		return "", "", false
+	}
+	if m.unknownPolicy == IgnoreUnknown {
+		return "", state.Name, true
+	}
+	return "", m.sink, true
+}
+
+// knownSymbol tests if the symbol is part of the alphabet of the DFA.
+func (m *DFA) knownSymbol(symbol string) bool {
+	if m.Alphabet != nil {
+		return contains(m.Alphabet, symbol)
+	}
+	m.ensureIndexed()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.EdgeLookup[symbol]
+	return ok
+}
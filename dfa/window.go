@@ -0,0 +1,135 @@
+package dfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const errWindow = "window guard"
+
+// History records when a Runner was fed its recent tokens. Every symbol
+// keeps the times of its last tokens in a ring buffer of fixed capacity.
+type History struct {
+	capacity int
+	now      func() time.Time
+	rings    map[string]*ring
+}
+
+// ring holds the most recent times of a symbol, next is the oldest once
+// it is full.
+type ring struct {
+	times []time.Time
+	next  int
+}
+
+// SetHistory lets the runner record the times of its last capacity tokens
+// of every symbol, measured with its clock, for window guards (see AtLeast
+// and AtMost). A capacity of 0 stops recording and drops the history.
+func (r *Runner) SetHistory(capacity int) {
+	if capacity <= 0 {
+		r.history = nil
+		return
+	}
+	r.history = &History{capacity: capacity, now: func() time.Time { return r.clock.Now() },
+		rings: make(map[string]*ring)}
+}
+
+// History returns the history of the runner, nil if it does not record one.
+func (r *Runner) History() *History {
+	return r.history
+}
+
+// record adds a token to the history.
+func (h *History) record(symbol string) {
+	buffer, ok := h.rings[symbol]
+	if !ok {
+		buffer = &ring{}
+		h.rings[symbol] = buffer
+	}
+	if len(buffer.times) < h.capacity {
+		buffer.times = append(buffer.times, h.now())
+		return
+	}
+	buffer.times[buffer.next] = h.now()
+	buffer.next = (buffer.next + 1) % h.capacity
+}
+
+// copy returns a copy of the history measuring time with now.
+func (h *History) copy(now func() time.Time) *History {
+	c := &History{capacity: h.capacity, now: now, rings: make(map[string]*ring, len(h.rings))}
+	for symbol, buffer := range h.rings {
+		c.rings[symbol] = &ring{times: append([]time.Time{}, buffer.times...), next: buffer.next}
+	}
+	return c
+}
+
+// Count returns how many tokens of the symbol were fed within the duration
+// up to now, at most the capacity of the history.
+func (h *History) Count(symbol string, within time.Duration) int {
+	buffer, ok := h.rings[symbol]
+	if !ok {
+		return 0
+	}
+	since := h.now().Add(-within)
+	count := 0
+	for _, t := range buffer.times {
+		if t.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// historyKey is the context key of the history.
+type historyKey struct{}
+
+// HistoryFrom returns the history carried by the context, nil if none.
+func HistoryFrom(ctx context.Context) *History {
+	history, _ := ctx.Value(historyKey{}).(*History)
+	return history
+}
+
+// AtLeast returns a guard that allows a transition only if at least n
+// tokens of the symbol were fed within the duration, including the current
+// one, e.g. to enter a lockout state after 3 "retry" within 10 minutes. It
+// needs a runner recording a history with a capacity of at least n.
+func AtLeast(symbol string, n int, within time.Duration) Guard {
+	return func(ctx context.Context, _ string) error {
+		if count := countWithin(ctx, symbol, within); count < n {
+			return fmt.Errorf("%s: %d '%s' within %s, need at least %d", errWindow, count, symbol, within, n)
+		}
+		return nil
+	}
+}
+
+// AtMost returns a guard that allows a transition only if at most n tokens
+// of the symbol were fed within the duration, including the current one,
+// e.g. to throttle. It needs a history like AtLeast with a capacity above n.
+func AtMost(symbol string, n int, within time.Duration) Guard {
+	return func(ctx context.Context, _ string) error {
+		if count := countWithin(ctx, symbol, within); count > n {
+			return fmt.Errorf("%s: %d '%s' within %s, allowed at most %d", errWindow, count, symbol, within, n)
+		}
+		return nil
+	}
+}
+
+// countWithin counts the tokens of the symbol in the history of the context.
+func countWithin(ctx context.Context, symbol string, within time.Duration) int {
+	history := HistoryFrom(ctx)
+	if history == nil {
+		return 0
+	}
+	return history.Count(symbol, within)
+}
+
+// context returns a context carrying the variables and the history of the
+// runner for guards.
+func (r *Runner) context(ctx context.Context) context.Context {
+	ctx = WithVars(ctx, r.vars)
+	if r.history != nil {
+		ctx = context.WithValue(ctx, historyKey{}, r.history)
+	}
+	return ctx
+}
@@ -0,0 +1,275 @@
+// Package editor wraps a DFA with undoable edits, intended as the backend
+// of a graphical machine editor.
+package editor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+const (
+	errStateExists      = "state already exists"
+	errStateNotExistent = "state not existent"
+	errEdgeExists       = "transition already exists"
+	errEdgeNotExistent  = "transition not existent"
+	errNothingToUndo    = "nothing to undo"
+	errNothingToRedo    = "nothing to redo"
+)
+
+// EventKind tells how a command was executed.
+type EventKind int
+
+const (
+	// Applied reports a new command
+	Applied EventKind = iota
+	// Undone reports an undone command
+	Undone
+	// Redone reports a command that was executed again
+	Redone
+)
+
+// Event is emitted after every change of the machine.
+type Event struct {
+	Kind EventKind
+	// Command describes the command, e.g. "add state 'paid'"
+	Command string
+}
+
+// command is an edit that can be undone.
+type command struct {
+	description string
+	do          func()
+	undo        func()
+}
+
+// Editor applies edits to a DFA and keeps them on undo and redo stacks.
+// Every new edit clears the redo stack.
+type Editor struct {
+	machine   *dfa.DFA
+	undo      []command
+	redo      []command
+	listeners []func(Event)
+}
+
+// New creates an editor for the machine.
+func New(m *dfa.DFA) *Editor {
+	return &Editor{machine: m}
+}
+
+// Machine returns the edited machine.
+func (e *Editor) Machine() *dfa.DFA {
+	return e.machine
+}
+
+// OnChange registers a listener that is called after every change.
+func (e *Editor) OnChange(listener func(Event)) {
+	e.listeners = append(e.listeners, listener)
+}
+
+// AddState adds a new, non-final state.
+func (e *Editor) AddState(name string) error {
+	if e.machine.StateExists(name) {
+		return fmt.Errorf("%s '%s'", errStateExists, name)
+	}
+	if err := e.machine.NamingPolicy().CheckState(name); err != nil {
+		return err
+	}
+	state := dfa.NewState(name)
+	e.apply(command{
+		description: fmt.Sprintf("add state '%s'", name),
+		do:          func() { e.machine.SetState(state) },
+		undo:        func() { e.machine.RemoveState(name) },
+	})
+	return nil
+}
+
+// RemoveState removes a state together with all transitions into it.
+func (e *Editor) RemoveState(name string) error {
+	state := e.machine.GetState(name)
+	if state == nil {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, name)
+	}
+	type edge struct{ from, symbol string }
+	var incoming []edge
+	for _, from := range e.machine.StatesSorted() {
+		for _, symbol := range e.machine.SymbolsSorted(from) {
+			if from != name && e.machine.GetState(from).Transitions[symbol] == name {
+				incoming = append(incoming, edge{from, symbol})
+			}
+		}
+	}
+	e.apply(command{
+		description: fmt.Sprintf("remove state '%s'", name),
+		do: func() {
+			for _, edge := range incoming {
+				e.machine.RemoveTransition(edge.from, edge.symbol)
+			}
+			e.machine.RemoveState(name)
+		},
+		undo: func() {
+			e.machine.SetState(state)
+			for _, edge := range incoming {
+				e.machine.AddTransition(edge.from, edge.symbol, name)
+			}
+		},
+	})
+	return nil
+}
+
+// AddEdge adds a transition for the symbol between two states.
+func (e *Editor) AddEdge(from, symbol, to string) error {
+	source, _, err := e.states(from, to)
+	if err != nil {
+		return err
+	}
+	if _, ok := source.Transitions[symbol]; ok {
+		return fmt.Errorf("%s '%s' at state '%s'", errEdgeExists, symbol, from)
+	}
+	if err := e.machine.NamingPolicy().CheckSymbol(symbol); err != nil {
+		return err
+	}
+	e.apply(command{
+		description: fmt.Sprintf("add transition '%s' from '%s' to '%s'", symbol, from, to),
+		do:          func() { e.machine.AddTransition(from, symbol, to) },
+		undo:        func() { e.machine.RemoveTransition(from, symbol) },
+	})
+	return nil
+}
+
+// RemoveEdge removes the transition of the symbol from a state.
+func (e *Editor) RemoveEdge(from, symbol string) error {
+	to, err := e.edge(from, symbol)
+	if err != nil {
+		return err
+	}
+	e.apply(command{
+		description: fmt.Sprintf("remove transition '%s' from '%s'", symbol, from),
+		do:          func() { e.machine.RemoveTransition(from, symbol) },
+		undo:        func() { e.machine.AddTransition(from, symbol, to) },
+	})
+	return nil
+}
+
+// MoveEdge lets the transition of the symbol lead to another state.
+func (e *Editor) MoveEdge(from, symbol, to string) error {
+	previous, err := e.edge(from, symbol)
+	if err != nil {
+		return err
+	}
+	if !e.machine.StateExists(to) {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, to)
+	}
+	e.apply(command{
+		description: fmt.Sprintf("move transition '%s' from '%s' to '%s'", symbol, from, to),
+		do:          func() { e.machine.AddTransition(from, symbol, to) },
+		undo:        func() { e.machine.AddTransition(from, symbol, previous) },
+	})
+	return nil
+}
+
+// ToggleFinal switches a state between final and non-final.
+func (e *Editor) ToggleFinal(name string) error {
+	state := e.machine.GetState(name)
+	if state == nil {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, name)
+	}
+	toggle := func() { state.SetFinal(!state.Final) }
+	e.apply(command{
+		description: fmt.Sprintf("toggle final of state '%s'", name),
+		do:          toggle,
+		undo:        toggle,
+	})
+	return nil
+}
+
+// SetStart sets the start state.
+func (e *Editor) SetStart(name string) error {
+	if !e.machine.StateExists(name) {
+		return fmt.Errorf("%s '%s'", errStateNotExistent, name)
+	}
+	previous := e.machine.Start
+	e.apply(command{
+		description: fmt.Sprintf("set start to '%s'", name),
+		do:          func() { e.machine.SetStart(name) },
+		undo:        func() { e.machine.SetStart(previous) },
+	})
+	return nil
+}
+
+// CanUndo tests if there is a command to undo.
+func (e *Editor) CanUndo() bool {
+	return len(e.undo) > 0
+}
+
+// CanRedo tests if there is a command to redo.
+func (e *Editor) CanRedo() bool {
+	return len(e.redo) > 0
+}
+
+// Undo reverts the last command.
+func (e *Editor) Undo() error {
+	if len(e.undo) == 0 {
+		return errors.New(errNothingToUndo)
+	}
+	c := e.undo[len(e.undo)-1]
+	e.undo = e.undo[:len(e.undo)-1]
+	c.undo()
+	e.redo = append(e.redo, c)
+	e.emit(Event{Kind: Undone, Command: c.description})
+	return nil
+}
+
+// Redo executes the last undone command again.
+func (e *Editor) Redo() error {
+	if len(e.redo) == 0 {
+		return errors.New(errNothingToRedo)
+	}
+	c := e.redo[len(e.redo)-1]
+	e.redo = e.redo[:len(e.redo)-1]
+	c.do()
+	e.undo = append(e.undo, c)
+	e.emit(Event{Kind: Redone, Command: c.description})
+	return nil
+}
+
+// apply executes a new command.
+func (e *Editor) apply(c command) {
+	c.do()
+	e.undo = append(e.undo, c)
+	e.redo = nil
+	e.emit(Event{Kind: Applied, Command: c.description})
+}
+
+func (e *Editor) emit(event Event) {
+	for _, listener := range e.listeners {
+		listener(event)
+	}
+}
+
+// states returns two existing states.
+func (e *Editor) states(from, to string) (*dfa.State, *dfa.State, error) {
+	source := e.machine.GetState(from)
+	if source == nil {
+		return nil, nil, fmt.Errorf("%s '%s'", errStateNotExistent, from)
+	}
+	target := e.machine.GetState(to)
+	if target == nil {
+		return nil, nil, fmt.Errorf("%s '%s'", errStateNotExistent, to)
+	}
+	return source, target, nil
+}
+
+// edge returns the target of an existing transition.
+func (e *Editor) edge(from, symbol string) (string, error) {
+	source := e.machine.GetState(from)
+	if source == nil {
+		return "", fmt.Errorf("%s '%s'", errStateNotExistent, from)
+	}
+	to, ok := source.Transitions[symbol]
+	if !ok {
+		return "", fmt.Errorf("%s '%s' at state '%s'", errEdgeNotExistent, symbol, from)
+	}
+	return to, nil
+}
@@ -0,0 +1,326 @@
+// Package expr evaluates small, sandboxed expressions over variables, used
+// for guards and assignments in serialized machine definitions.
+//
+// Expressions support numbers, strings in double quotes, true and false,
+// variables, the arithmetic operators + - * / %, comparisons (== != < <=
+// > >=), the logical operators && || ! and parentheses, e.g.
+// "attempts < 3 && amount > 100". Assignments are separated by ";" and use
+// =, += or -=, e.g. "attempts += 1; last = amount". Numbers are float64.
+// Missing variables evaluate to nil, which counts as 0 in arithmetic and
+// comparisons with numbers.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	errNotBoolean      = "expr: result is not a boolean"
+	errDivisionZero    = "expr: division by zero"
+	errTypeMismatch    = "expr: type mismatch"
+	errUnknownOperator = "expr: unknown operator"
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	source string
+	root   *node
+}
+
+// Assignment is a parsed list of assignments.
+type Assignment struct {
+	source  string
+	targets []assignTarget
+}
+
+// assignTarget is one assignment of a variable.
+type assignTarget struct {
+	name     string
+	operator string
+	value    *node
+}
+
+// node is a node of the syntax tree.
+type node struct {
+	operator string
+	value    any
+	name     string
+	children []*node
+}
+
+// Parse parses an expression.
+func Parse(source string) (*Expr, error) {
+	p, err := newParser(source)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// MustParse parses an expression and panics on errors.
+func MustParse(source string) *Expr {
+	e, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// ParseAssignment parses assignments like "attempts += 1; last = amount".
+func ParseAssignment(source string) (*Assignment, error) {
+	p, err := newParser(source)
+	if err != nil {
+		return nil, err
+	}
+	assignment := &Assignment{source: source}
+	for !p.done() {
+		name := p.tokens[p.pos]
+		if name.kind != identToken {
+			return nil, p.errorf("expected variable")
+		}
+		p.pos++
+		operator := p.operator()
+		if operator != "=" && operator != "+=" && operator != "-=" {
+			return nil, p.errorf("expected '=', '+=' or '-='")
+		}
+		p.pos++
+		value, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		assignment.targets = append(assignment.targets, assignTarget{name.text, operator, value})
+		if p.operator() == ";" {
+			p.pos++
+		} else if !p.done() {
+			return nil, p.errorf("expected ';'")
+		}
+	}
+	return assignment, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression with the variables.
+func (e *Expr) Eval(vars map[string]any) (any, error) {
+	return eval(e.root, vars)
+}
+
+// Bool evaluates the expression, which has to result in a boolean.
+func (e *Expr) Bool(vars map[string]any) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, errors.New(errNotBoolean)
+	}
+	return result, nil
+}
+
+// String returns the source of the assignment.
+func (a *Assignment) String() string {
+	return a.source
+}
+
+// Apply evaluates the assignments in order and stores their results in
+// the variables. Nothing is stored if an assignment fails.
+func (a *Assignment) Apply(vars map[string]any) error {
+	results := make(map[string]any)
+	lookup := func(name string) any {
+		if value, ok := results[name]; ok {
+			return value
+		}
+		return vars[name]
+	}
+	for _, target := range a.targets {
+		value, err := evalWith(target.value, lookup)
+		if err != nil {
+			return err
+		}
+		if target.operator != "=" {
+			value, err = arithmetic(target.operator[:1], lookup(target.name), value)
+			if err != nil {
+				return err
+			}
+		}
+		results[target.name] = value
+	}
+	for name, value := range results {
+		vars[name] = value
+	}
+	return nil
+}
+
+func eval(n *node, vars map[string]any) (any, error) {
+	return evalWith(n, func(name string) any { return vars[name] })
+}
+
+// evalWith evaluates a node, looking variables up with the function.
+func evalWith(n *node, lookup func(string) any) (any, error) {
+	switch n.operator {
+	case "":
+		if n.name != "" {
+			return normalize(lookup(n.name)), nil
+		}
+		return n.value, nil
+	case "&&", "||":
+		left, err := evalBool(n.children[0], lookup)
+		if err != nil {
+			return nil, err
+		}
+		if left == (n.operator == "||") {
+			return left, nil
+		}
+		return evalBool(n.children[1], lookup)
+	case "!":
+		value, err := evalBool(n.children[0], lookup)
+		return !value, err
+	case "neg":
+		value, err := evalWith(n.children[0], lookup)
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic("-", 0.0, value)
+	}
+	left, err := evalWith(n.children[0], lookup)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalWith(n.children[1], lookup)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<", "<=", ">", ">=":
+		return compare(n.operator, left, right)
+	}
+	return arithmetic(n.operator, left, right)
+}
+
+func evalBool(n *node, lookup func(string) any) (bool, error) {
+	value, err := evalWith(n, lookup)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, errors.New(errNotBoolean)
+	}
+	return result, nil
+}
+
+// normalize converts the numbers of variables to float64.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+// compare compares two numbers or two strings.
+func compare(operator string, left, right any) (bool, error) {
+	if _, ok := right.(float64); ok && left == nil {
+		left = 0.0
+	}
+	if _, ok := left.(float64); ok && right == nil {
+		right = 0.0
+	}
+	var c int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("%s: %v %s %v", errTypeMismatch, left, operator, right)
+		}
+		c = compareOrdered(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("%s: %v %s %v", errTypeMismatch, left, operator, right)
+		}
+		c = compareOrdered(l, r)
+	default:
+		return false, fmt.Errorf("%s: %v %s %v", errTypeMismatch, left, operator, right)
+	}
+	switch operator {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func compareOrdered[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// arithmetic applies an arithmetic operator, + also concatenates strings.
+func arithmetic(operator string, left, right any) (any, error) {
+	left, right = normalize(left), normalize(right)
+	if l, ok := left.(string); ok && operator == "+" {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	}
+	l, ok := left.(float64)
+	r, ok2 := right.(float64)
+	if left == nil && ok2 {
+		l, ok = 0, true
+	}
+	if right == nil && ok {
+		r, ok2 = 0, true
+	}
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("%s: %v %s %v", errTypeMismatch, left, operator, right)
+	}
+	switch operator {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, errors.New(errDivisionZero)
+		}
+		if operator == "/" {
+			return l / r, nil
+		}
+		return math.Mod(l, r), nil
+	}
+	return nil, fmt.Errorf("%s '%s'", errUnknownOperator, operator)
+}
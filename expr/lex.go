@@ -0,0 +1,86 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// operators holds the operators, longer ones first so they match first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "+=", "-=",
+	"<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "=", ";"}
+
+const (
+	numberToken = iota
+	stringToken
+	identToken
+	operatorToken
+)
+
+// token is a lexical token of an expression.
+type token struct {
+	kind   int
+	text   string
+	number float64
+	pos    int
+}
+
+// lex splits an expression into tokens.
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("expr: invalid number '%s' at position %d", source[start:i], start)
+			}
+			tokens = append(tokens, token{kind: numberToken, text: source[start:i], number: number, pos: start})
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("expr: unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("expr: invalid string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: stringToken, text: text, pos: i})
+			i = end + 1
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: identToken, text: source[start:i], pos: start})
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(source[i:], operator) {
+					tokens = append(tokens, token{kind: operatorToken, text: operator, pos: i})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("expr: unexpected '%c' at position %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
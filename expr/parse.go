@@ -0,0 +1,143 @@
+package expr
+
+import "fmt"
+
+// parser is a recursive descent parser of expressions.
+type parser struct {
+	tokens []token
+	pos    int
+	length int
+}
+
+func newParser(source string) (*parser, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, length: len(source)}, nil
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// operator returns the current operator or "" for other tokens and the end.
+func (p *parser) operator() string {
+	if p.done() || p.tokens[p.pos].kind != operatorToken {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// binary parses a left-associative chain of the operators.
+func (p *parser) binary(next func() (*node, error), operators ...string) (*node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := p.operator()
+		if !contains(operators, operator) {
+			return left, nil
+		}
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{operator: operator, children: []*node{left, right}}
+	}
+}
+
+func (p *parser) or() (*node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (*node, error) {
+	return p.binary(p.comparison, "&&")
+}
+
+func (p *parser) comparison() (*node, error) {
+	return p.binary(p.sum, "==", "!=", "<", "<=", ">", ">=")
+}
+
+func (p *parser) sum() (*node, error) {
+	return p.binary(p.product, "+", "-")
+}
+
+func (p *parser) product() (*node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+func (p *parser) unary() (*node, error) {
+	switch p.operator() {
+	case "!", "-":
+		operator := p.operator()
+		if operator == "-" {
+			operator = "neg"
+		}
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{operator: operator, children: []*node{operand}}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (*node, error) {
+	if p.done() {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case numberToken:
+		p.pos++
+		return &node{value: t.number}, nil
+	case stringToken:
+		p.pos++
+		return &node{value: t.text}, nil
+	case identToken:
+		p.pos++
+		switch t.text {
+		case "true":
+			return &node{value: true}, nil
+		case "false":
+			return &node{value: false}, nil
+		case "nil":
+			return &node{}, nil
+		}
+		return &node{name: t.text}, nil
+	}
+	if t.text == "(" {
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.operator() != ")" {
+			return nil, p.errorf("missing ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return nil, p.errorf("unexpected '%s'", t.text)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	pos := p.length
+	if p.pos < len(p.tokens) {
+		pos = p.tokens[p.pos].pos
+	}
+	return fmt.Errorf("expr: %s at position %d", fmt.Sprintf(format, args...), pos)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package mining
+
+import (
+	"sort"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// Deviation is a symbol of a trace the machine could not replay, or the end
+// of a trace that did not leave the machine in a final state, in which case
+// Symbol is empty and Position is the length of the trace.
+type Deviation struct {
+	Position int
+	State    string
+	Symbol   string
+}
+
+// TraceConformance holds the conformance of one trace.
+type TraceConformance struct {
+	Key        string
+	Fitness    float64
+	Deviations []Deviation
+}
+
+// Conformance compares an event log with a machine. Fitness is the share
+// of the symbols and trace ends that could be replayed, 1 if the machine
+// can replay the whole log. Precision is the share of the transitions
+// enabled in the visited states that the log actually used, 1 if the
+// machine allows no behavior beyond the log.
+type Conformance struct {
+	Fitness   float64
+	Precision float64
+	// Traces is sorted by key
+	Traces []TraceConformance
+}
+
+// CheckConformance replays the traces on the machine in the style of token
+// replay: symbols without a transition are skipped and counted as
+// deviations, as are traces not ending in a final state. Hooks and actions
+// of the machine are not executed.
+func CheckConformance(m *dfa.DFA, traces map[string][]string) *Conformance {
+	result := &Conformance{Fitness: 1, Precision: 1}
+	keys := make([]string, 0, len(traces))
+	for key := range traces {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// used holds the symbols replayed per state, visits how often states were visited
+	used := make(map[string]map[string]bool)
+	visits := make(map[string]int)
+	steps, deviations := 0, 0
+	for _, key := range keys {
+		trace := traces[key]
+		r := dfa.NewRunner(m)
+		r.SetDryRun(true, nil)
+		conformance := TraceConformance{Key: key}
+		for i, symbol := range trace {
+			state := r.Current()
+			visits[state]++
+			if _, ok, err := r.Feed(symbol); err != nil || !ok {
+				conformance.Deviations = append(conformance.Deviations, Deviation{Position: i, State: state, Symbol: symbol})
+				continue
+			}
+			if used[state] == nil {
+				used[state] = make(map[string]bool)
+			}
+			used[state][symbol] = true
+		}
+		if !r.IsFinal() {
+			conformance.Deviations = append(conformance.Deviations, Deviation{Position: len(trace), State: r.Current()})
+		}
+		conformance.Fitness = 1 - float64(len(conformance.Deviations))/float64(len(trace)+1)
+		steps += len(trace) + 1
+		deviations += len(conformance.Deviations)
+		result.Traces = append(result.Traces, conformance)
+	}
+	if steps > 0 {
+		result.Fitness = 1 - float64(deviations)/float64(steps)
+	}
+	enabled, escaping := 0, 0
+	for state, count := range visits {
+		s := m.GetState(state)
+		if s == nil {
+			continue
+		}
+		for symbol := range s.Transitions {
+			enabled += count
+			if !used[state][symbol] {
+				escaping += count
+			}
+		}
+	}
+	if enabled > 0 {
+		result.Precision = 1 - float64(escaping)/float64(enabled)
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+// Package mining discovers processes from event logs: it builds the
+// directly-follows graph of the traces, filters rare behavior and infers a
+// DFA that can be compared with the designed machine (see dfa.DiffMachines).
+package mining
+
+import (
+	"sort"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// StartState is the name of the state discovered machines start in.
+const StartState = "start"
+
+// Pair is an activity directly followed by another one in a trace.
+type Pair struct {
+	From string
+	To   string
+}
+
+// Graph is a directly-follows graph: how often activities occur, start
+// and end traces and directly follow each other.
+type Graph struct {
+	Traces     int
+	Activities map[string]int
+	Starts     map[string]int
+	Ends       map[string]int
+	Follows    map[Pair]int
+}
+
+// Options are the filtering thresholds of Filter. Activities and pairs
+// occurring less often than the minimum counts are dropped, as are pairs
+// making up less than MinRatio of the pairs leaving their activity.
+type Options struct {
+	MinActivity int
+	MinFollows  int
+	MinRatio    float64
+}
+
+// Traces groups the events by key into traces of symbols ordered by time.
+func Traces(events []dfa.Event) map[string][]string {
+	ordered := append([]dfa.Event{}, events...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Time.Before(ordered[j].Time) })
+	traces := make(map[string][]string)
+	for _, event := range ordered {
+		traces[event.Key] = append(traces[event.Key], event.Symbol)
+	}
+	return traces
+}
+
+// DirectlyFollows builds the directly-follows graph of the traces.
+func DirectlyFollows(traces map[string][]string) *Graph {
+	g := newGraph()
+	for _, trace := range traces {
+		if len(trace) == 0 {
+			continue
+		}
+		g.Traces++
+		g.Starts[trace[0]]++
+		g.Ends[trace[len(trace)-1]]++
+		for i, activity := range trace {
+			g.Activities[activity]++
+			if i > 0 {
+				g.Follows[Pair{trace[i-1], activity}]++
+			}
+		}
+	}
+	return g
+}
+
+func newGraph() *Graph {
+	return &Graph{
+		Activities: make(map[string]int),
+		Starts:     make(map[string]int),
+		Ends:       make(map[string]int),
+		Follows:    make(map[Pair]int),
+	}
+}
+
+// Filter returns a copy of the graph without the activities and pairs below
+// the thresholds.
+func (g *Graph) Filter(options Options) *Graph {
+	filtered := newGraph()
+	filtered.Traces = g.Traces
+	for activity, count := range g.Activities {
+		if count >= options.MinActivity {
+			filtered.Activities[activity] = count
+		}
+	}
+	for activity, count := range g.Starts {
+		if _, ok := filtered.Activities[activity]; ok {
+			filtered.Starts[activity] = count
+		}
+	}
+	for activity, count := range g.Ends {
+		if _, ok := filtered.Activities[activity]; ok {
+			filtered.Ends[activity] = count
+		}
+	}
+	leaving := make(map[string]int)
+	for pair, count := range g.Follows {
+		leaving[pair.From] += count
+	}
+	for pair, count := range g.Follows {
+		_, from := filtered.Activities[pair.From]
+		_, to := filtered.Activities[pair.To]
+		if !from || !to || count < options.MinFollows ||
+			float64(count) < options.MinRatio*float64(leaving[pair.From]) {
+			continue
+		}
+		filtered.Follows[pair] = count
+	}
+	return filtered
+}
+
+// DFA infers a machine from the graph: a state per activity, entered by the
+// activity, and the start state StartState. States of activities that end
+// traces are final. The weights of the transitions are their relative
+// frequencies among the transitions of their state.
+func (g *Graph) DFA(name string) *dfa.DFA {
+	m := dfa.NewDFA(name)
+	states := map[string]*dfa.State{StartState: dfa.NewState(StartState)}
+	for activity := range g.Activities {
+		states[activity] = dfa.NewState(activity)
+		states[activity].SetFinal(g.Ends[activity] > 0)
+	}
+	hits := make(map[dfa.Hit]int64)
+	for activity, count := range g.Starts {
+		states[StartState].AddTransition(states[activity], activity)
+		hits[dfa.Hit{From: StartState, Symbol: activity, To: activity}] = int64(count)
+	}
+	for pair, count := range g.Follows {
+		states[pair.From].AddTransition(states[pair.To], pair.To)
+		hits[dfa.Hit{From: pair.From, Symbol: pair.To, To: pair.To}] = int64(count)
+	}
+	for _, state := range states {
+		m.SetState(state)
+	}
+	m.SetStart(StartState)
+	m.SetFrequencies(hits)
+	return m
+}
+
+// Discover infers a machine from events, see Traces, DirectlyFollows,
+// Filter and DFA.
+func Discover(name string, events []dfa.Event, options Options) *dfa.DFA {
+	return DirectlyFollows(Traces(events)).Filter(options).DFA(name)
+}
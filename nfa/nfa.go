@@ -0,0 +1,212 @@
+// Package nfa implements nondeterministic finite automata with epsilon
+// transitions and their conversion into deterministic ones.
+package nfa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// NFA holds everything that is needed in order to execute the automaton.
+type NFA struct {
+	Name string
+	// States holds the state name as well as the state structure
+	States map[string]*State
+	Start  string
+	// progress is called while the NFA is converted
+	progress func(done, total int64)
+}
+
+// NewNFA creates a new NFA
+func NewNFA(name string) *NFA {
+	return &NFA{
+		Name:   name,
+		States: make(map[string]*State),
+	}
+}
+
+// SetStart sets the starting point of the NFA.
+func (m *NFA) SetStart(state string) {
+	m.Start = state
+}
+
+// GetStart returns the starting point of the NFA.
+func (m *NFA) GetStart() string {
+	return m.Start
+}
+
+// SetState sets one state
+func (m *NFA) SetState(state *State) {
+	if m.States == nil {
+		m.States = make(map[string]*State)
+	}
+	m.States[state.Name] = state
+}
+
+// SetStates is able to set multiple states at once
+func (m *NFA) SetStates(states []*State) {
+	for _, state := range states {
+		m.SetState(state)
+	}
+}
+
+// GetState returns the specific state with a given name
+func (m *NFA) GetState(name string) *State {
+	return m.States[name]
+}
+
+// StateExists tests if the state exists
+func (m *NFA) StateExists(name string) bool {
+	return m.States[name] != nil
+}
+
+// Closure returns the sorted set of states reachable from the given
+// states by epsilon transitions, including the states themselves.
+func (m *NFA) Closure(states []string) []string {
+	visited := make(map[string]bool)
+	stack := append([]string{}, states...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		if state := m.GetState(name); state != nil {
+			stack = append(stack, state.Epsilon...)
+		}
+	}
+	closure := make([]string, 0, len(visited))
+	for name := range visited {
+		closure = append(closure, name)
+	}
+	sort.Strings(closure)
+	return closure
+}
+
+// Step returns the closed set of states reachable from the given states
+// by consuming the symbol.
+func (m *NFA) Step(states []string, symbol string) []string {
+	var targets []string
+	for _, name := range states {
+		if state := m.GetState(name); state != nil {
+			targets = append(targets, state.Via(symbol)...)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return m.Closure(targets)
+}
+
+// Run tests if the tokens lead from the start state into a final state
+// on any of the possible paths.
+func (m *NFA) Run(tokens []string) bool {
+	current := m.Closure([]string{m.Start})
+	for _, token := range tokens {
+		current = m.Step(current, token)
+		if len(current) == 0 {
+			return false
+		}
+	}
+	return m.anyFinal(current)
+}
+
+// GetSymbols returns the sorted distinct symbols used in this NFA
+func (m *NFA) GetSymbols() []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, state := range m.States {
+		for symbol := range state.Transitions {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// SetProgress sets a function called while ToDFA proceeds with the number
+// of converted and of discovered sets of states, which grows until the
+// conversion is done. Passing nil removes it.
+func (m *NFA) SetProgress(progress func(done, total int64)) {
+	m.progress = progress
+}
+
+// ToDFA converts the NFA into an equivalent DFA using the subset
+// construction. Every DFA state represents the set of NFA states it was
+// built from and is named after them, e.g. "{a,b}"; sets of one state
+// keep the name of the state. Only reachable sets become states, symbols
+// leading to the empty set have no transition.
+func (m *NFA) ToDFA() *dfa.DFA {
+	result := dfa.NewDFA(m.Name)
+	symbols := m.GetSymbols()
+	names := make(map[string]*dfa.State)
+	used := make(map[string]bool)
+	var queue [][]string
+
+	visit := func(set []string) *dfa.State {
+		key := strings.Join(set, "\x00")
+		if state, ok := names[key]; ok {
+			return state
+		}
+		state := dfa.NewState(uniqueName(setName(set), used))
+		state.SetFinal(m.anyFinal(set))
+		names[key] = state
+		result.SetState(state)
+		queue = append(queue, set)
+		return state
+	}
+
+	result.SetStart(visit(m.Closure([]string{m.Start})).Name)
+	for done := int64(0); len(queue) > 0; done++ {
+		if m.progress != nil && done%1024 == 0 {
+			m.progress(done, done+int64(len(queue)))
+		}
+		set := queue[0]
+		queue = queue[1:]
+		from := names[strings.Join(set, "\x00")]
+		for _, symbol := range symbols {
+			if next := m.Step(set, symbol); len(next) > 0 {
+				from.AddTransition(visit(next), symbol)
+			}
+		}
+	}
+	if m.progress != nil {
+		m.progress(int64(len(names)), int64(len(names)))
+	}
+	return result
+}
+
+// anyFinal tests if any of the states is final.
+func (m *NFA) anyFinal(states []string) bool {
+	for _, name := range states {
+		if state := m.GetState(name); state != nil && state.Final {
+			return true
+		}
+	}
+	return false
+}
+
+// setName names a DFA state after its set of NFA states.
+func setName(set []string) string {
+	if len(set) == 1 {
+		return set[0]
+	}
+	return "{" + strings.Join(set, ",") + "}"
+}
+
+// uniqueName returns the name or, if already taken, the name with a suffix.
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 1; used[candidate]; i++ {
+		candidate = name + "#" + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,72 @@
+package nfa
+
+// State of a nondeterministic automaton.
+type State struct {
+	// Name represents the name of the state
+	Name string
+	// Transitions represents the transitions of the state.
+	// The map is structured map[Symbol][]State
+	Transitions map[string][]string
+	// Epsilon holds the states reachable without consuming a symbol
+	Epsilon []string
+	Final   bool
+}
+
+// NewState creates a new state
+func NewState(name string) *State {
+	return &State{
+		Name:        name,
+		Final:       false,
+		Transitions: make(map[string][]string),
+	}
+}
+
+// GetTransitions returns the symbols that would lead to a transition
+func (s *State) GetTransitions() map[string][]string {
+	return s.Transitions
+}
+
+// AddTransitions adds a bulk of symbols to the state that all lead to the same state
+func (s *State) AddTransitions(state *State, symbols []string) {
+	for _, symbol := range symbols {
+		s.AddTransition(state, symbol)
+	}
+}
+
+// AddTransition adds a symbol that leads to a state, a symbol may lead to several states
+func (s *State) AddTransition(state *State, symbol string) {
+	if s.Transitions == nil {
+		s.Transitions = make(map[string][]string)
+	}
+	for _, existing := range s.Transitions[symbol] {
+		if existing == state.Name {
+			return
+		}
+	}
+	s.Transitions[symbol] = append(s.Transitions[symbol], state.Name)
+}
+
+// AddEpsilon adds a transition to a state that does not consume a symbol
+func (s *State) AddEpsilon(state *State) {
+	for _, existing := range s.Epsilon {
+		if existing == state.Name {
+			return
+		}
+	}
+	s.Epsilon = append(s.Epsilon, state.Name)
+}
+
+// Via returns all states a symbol leads to
+func (s *State) Via(symbol string) []string {
+	return s.Transitions[symbol]
+}
+
+// IsFinal tests if this state is a final state
+func (s *State) IsFinal() bool {
+	return s.Final
+}
+
+// SetFinal sets this state to a final state
+func (s *State) SetFinal(final bool) {
+	s.Final = final
+}
@@ -0,0 +1,187 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/breskos/gopher-state/wire"
+)
+
+const errNoNodes = "no nodes in ring"
+
+// ErrMoved is returned if the owner of an instance key changed since its
+// session was opened.
+var ErrMoved = errors.New("instance key moved to another node")
+
+// Dialer opens a multiplexed connection to the given node (see
+// wire.Server.ServeMux).
+type Dialer func(node string) (*wire.MuxClient, error)
+
+// Forwarder drives instances on the node owning their key. The instances of
+// a node share one multiplexed connection, opened on first use, every
+// instance key gets its own session on it. Sessions do not follow ring
+// changes and are not reopened: once a key is owned by another node, Step
+// returns ErrMoved, once the connection of a session failed, Step returns
+// the failure. Both persist until the caller closes the session to
+// reattach the key explicitly, e.g. after moving the instance to the new
+// owner, instead of silently starting over on a new session.
+type Forwarder struct {
+	ring     *Ring
+	dial     Dialer
+	mu       sync.Mutex
+	conns    map[string]*conn
+	sessions map[string]*session
+	// next is the ID of the last opened session
+	next uint64
+}
+
+// conn is the multiplexed connection to a node, err is set once it failed.
+type conn struct {
+	node     string
+	client   *wire.MuxClient
+	err      error
+	sessions int
+}
+
+// session is an open session of an instance key.
+type session struct {
+	node string
+	id   uint64
+	conn *conn
+}
+
+// NewForwarder creates a new forwarder on the ring.
+func NewForwarder(ring *Ring, dial Dialer) *Forwarder {
+	return &Forwarder{
+		ring:     ring,
+		dial:     dial,
+		conns:    make(map[string]*conn),
+		sessions: make(map[string]*session),
+	}
+}
+
+// Step forwards a symbol ID for the instance key to its node. The error
+// wraps ErrMoved if the key is owned by another node than its session, and
+// the failure of the connection once it failed.
+func (f *Forwarder) Step(key string, symbolID uint64) (wire.Status, uint64, error) {
+	s, err := f.session(key)
+	if err != nil {
+		return 0, 0, err
+	}
+	status, state, err := s.conn.client.Step(s.id, symbolID)
+	if err != nil {
+		f.fail(s.conn, err)
+	}
+	return status, state, err
+}
+
+// Close closes the session of an instance key, the next Step opens a new
+// session on the current owner. The connection is closed with its last
+// session.
+func (f *Forwarder) Close(key string) error {
+	f.mu.Lock()
+	s, ok := f.sessions[key]
+	if !ok {
+		f.mu.Unlock()
+		return nil
+	}
+	delete(f.sessions, key)
+	c := s.conn
+	c.sessions--
+	last, failed := c.sessions == 0, c.err != nil
+	if last && f.conns[c.node] == c {
+		delete(f.conns, c.node)
+	}
+	f.mu.Unlock()
+	switch {
+	case failed:
+		return nil
+	case last:
+		return c.client.Close()
+	}
+	if err := c.client.Release(s.id); err != nil {
+		f.fail(c, err)
+		return err
+	}
+	return nil
+}
+
+// session returns the session of a key, opening it on the current owner if
+// there is none.
+func (f *Forwarder) session(key string) (*session, error) {
+	node := f.ring.Get(key)
+	if node == "" {
+		return nil, errors.New(errNoNodes)
+	}
+	f.mu.Lock()
+	s, ok := f.sessions[key]
+	f.mu.Unlock()
+	for !ok {
+		c, err := f.conn(node)
+		if err != nil {
+			return nil, err
+		}
+		f.mu.Lock()
+		// another step of the key may have opened a session meanwhile, the
+		// connection may have been closed with its last session or failed
+		if s, ok = f.sessions[key]; !ok && f.conns[node] == c {
+			f.next++
+			s = &session{node: node, id: f.next, conn: c}
+			c.sessions++
+			f.sessions[key] = s
+			ok = true
+		}
+		f.mu.Unlock()
+	}
+	if s.node != node {
+		return nil, fmt.Errorf("%w: '%s' from %s to %s", ErrMoved, key, s.node, node)
+	}
+	f.mu.Lock()
+	err := s.conn.err
+	f.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("session of '%s' on %s: %w", key, s.node, err)
+	}
+	return s, nil
+}
+
+// conn returns the connection to a node, dialing it if there is none. The
+// dial happens outside the lock.
+func (f *Forwarder) conn(node string) (*conn, error) {
+	f.mu.Lock()
+	c, ok := f.conns[node]
+	f.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	client, err := f.dial(node)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// another key of the node may have connected meanwhile
+	if c, ok := f.conns[node]; ok {
+		client.Close()
+		return c, nil
+	}
+	c = &conn{node: node, client: client}
+	f.conns[node] = c
+	return c, nil
+}
+
+// fail marks the connection as failed and closes it, its sessions keep
+// failing until they are closed while new keys of the node connect again.
+func (f *Forwarder) fail(c *conn, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.client.Close()
+	if f.conns[c.node] == c {
+		delete(f.conns, c.node)
+	}
+}
@@ -0,0 +1,101 @@
+// Package shard maps instance keys onto a fleet of gopher-state servers
+// using consistent hashing.
+package shard
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of virtual nodes per node if none is given.
+const DefaultReplicas = 64
+
+// Ring is a consistent-hashing ring of nodes. It is safe for concurrent use.
+type Ring struct {
+	replicas int
+	mu       sync.RWMutex
+	hashes   []uint32
+	owners   map[uint32]string
+	nodes    map[string]bool
+}
+
+// NewRing creates a new ring placing every node replicas times on the ring.
+func NewRing(replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	return &Ring{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]bool),
+	}
+}
+
+// Add adds nodes to the ring.
+func (r *Ring) Add(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, node := range nodes {
+		if r.nodes[node] {
+			continue
+		}
+		r.nodes[node] = true
+		for i := 0; i < r.replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + node))
+			if _, taken := r.owners[hash]; taken {
+				continue
+			}
+			r.owners[hash] = node
+			r.hashes = append(r.hashes, hash)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove removes a node from the ring, its keys move to the next nodes.
+func (r *Ring) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.nodes[node] {
+		return
+	}
+	delete(r.nodes, node)
+	hashes := r.hashes[:0]
+	for _, hash := range r.hashes {
+		if r.owners[hash] == node {
+			delete(r.owners, hash)
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	r.hashes = hashes
+}
+
+// Nodes returns the nodes of the ring in sorted order.
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Get returns the node owning the key, or an empty string if the ring is empty.
+func (r *Ring) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
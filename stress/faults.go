@@ -0,0 +1,116 @@
+package stress
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// ErrInjected is returned (or panicked with) by the faults of a plan that
+// does not name an error.
+var ErrInjected = errors.New("injected fault")
+
+// Point names a place where a fault can be injected.
+type Point string
+
+const (
+	// OutboxAdd, OutboxDone and OutboxPending are the calls of an outbox
+	OutboxAdd     Point = "outbox.add"
+	OutboxDone    Point = "outbox.done"
+	OutboxPending Point = "outbox.pending"
+	// Action is the execution of an action, which panics with the error
+	Action Point = "action"
+)
+
+// FaultPlan holds the calls that fail by point, counted from 1, e.g.
+// {OutboxDone: {2}} fails the second Done call.
+type FaultPlan struct {
+	Calls map[Point][]int
+	// Err is the injected error, ErrInjected by default
+	Err error
+}
+
+// Faults injects the faults of a plan into the outboxes and actions it
+// wraps, so recovery logic can be tested deterministically. Calls are
+// counted across all wrapped outboxes and actions. It is safe for
+// concurrent use.
+type Faults struct {
+	plan     FaultPlan
+	mu       sync.Mutex
+	calls    map[Point]int
+	injected int
+}
+
+// WithFaults creates the injector of the plan.
+func WithFaults(plan FaultPlan) *Faults {
+	if plan.Err == nil {
+		plan.Err = ErrInjected
+	}
+	return &Faults{plan: plan, calls: make(map[Point]int)}
+}
+
+// Injected returns the number of faults injected so far.
+func (f *Faults) Injected() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.injected
+}
+
+// fail counts a call of the point and returns the error if it fails.
+func (f *Faults) fail(point Point) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[point]++
+	for _, call := range f.plan.Calls[point] {
+		if call == f.calls[point] {
+			f.injected++
+			return f.plan.Err
+		}
+	}
+	return nil
+}
+
+// Outbox wraps the outbox, failing its calls as planned. Failed calls do
+// not reach the outbox.
+func (f *Faults) Outbox(outbox dfa.Outbox) dfa.Outbox {
+	return &faultyOutbox{faults: f, outbox: outbox}
+}
+
+// Action wraps the action, panicking with the error instead of executing
+// it as planned.
+func (f *Faults) Action(action func(ctx dfa.Context)) func(ctx dfa.Context) {
+	return func(ctx dfa.Context) {
+		if err := f.fail(Action); err != nil {
+			panic(err)
+		}
+		action(ctx)
+	}
+}
+
+// faultyOutbox is an outbox with injected faults.
+type faultyOutbox struct {
+	faults *Faults
+	outbox dfa.Outbox
+}
+
+func (o *faultyOutbox) Add(intent dfa.Intent) (string, error) {
+	if err := o.faults.fail(OutboxAdd); err != nil {
+		return "", err
+	}
+	return o.outbox.Add(intent)
+}
+
+func (o *faultyOutbox) Done(id string) error {
+	if err := o.faults.fail(OutboxDone); err != nil {
+		return err
+	}
+	return o.outbox.Done(id)
+}
+
+func (o *faultyOutbox) Pending() ([]dfa.Intent, error) {
+	if err := o.faults.fail(OutboxPending); err != nil {
+		return nil, err
+	}
+	return o.outbox.Pending()
+}
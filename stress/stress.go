@@ -0,0 +1,325 @@
+// Package stress runs many concurrent Runner instances of a machine with
+// random symbol streams and injected faults, and checks that no transition
+// is lost and no action is executed twice. Faults can also be injected at
+// planned points of outboxes and actions (see WithFaults).
+package stress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+const (
+	errNoSymbols = "no symbols to feed"
+	errCrash     = "injected crash"
+)
+
+// seqVar is the variable holding the number of tokens an instance
+// acknowledged, it tells apart the intents of different tokens.
+const seqVar = "stress.seq"
+
+// Config configures a stress run. Zero values use the defaults.
+type Config struct {
+	// Instances is the number of runners, 100 by default
+	Instances int
+	// Tokens is the number of tokens fed to every instance, 100 by default
+	Tokens int
+	// Workers is the number of goroutines feeding the instances, one per
+	// instance by default
+	Workers int
+	// Seed seeds the random symbol streams and faults. Instance i draws
+	// from its own source seeded with Seed+i, so the same config reproduces
+	// every instance bit-for-bit regardless of Workers and scheduling.
+	Seed int64
+	// Only restricts the run to the instances with these numbers, e.g. to
+	// replay a lost instance "instance-17" with Only: []int{17}
+	Only []int
+	// Symbols are fed at random, the symbols of the machine by default
+	Symbols []string
+	// StoreErrorRate is the probability that persisting the snapshot of an
+	// instance after a token fails
+	StoreErrorRate float64
+	// PanicRate is the probability that a transition panics after its
+	// action was executed, crashing the instance
+	PanicRate float64
+	// MaxAttempts is the number of times a token is delivered before it is
+	// dropped, 10 by default
+	MaxAttempts int
+}
+
+// Result is the outcome of a stress run.
+type Result struct {
+	Instances   int
+	Tokens      int
+	Transitions int
+	// Crashes counts the injected panics, StoreErrors the failed snapshots;
+	// the instances were restored from their last snapshot and the token
+	// delivered again
+	Crashes     int
+	StoreErrors int
+	// Dropped counts the tokens given up after MaxAttempts deliveries
+	Dropped int
+	// Lost holds the instances whose state differs from a runner fed the
+	// acknowledged tokens without faults
+	Lost []string
+	// Duplicates counts the actions executed more than once for the same
+	// token. Actions have at-least-once semantics, so crashes after an
+	// action cause duplicates that idempotent actions have to tolerate.
+	Duplicates int
+}
+
+// OK tests if no transition was lost and no action was duplicated.
+func (r *Result) OK() bool {
+	return len(r.Lost) == 0 && r.Duplicates == 0
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("instances=%d tokens=%d transitions=%d crashes=%d store-errors=%d dropped=%d lost=%d duplicates=%d",
+		r.Instances, r.Tokens, r.Transitions, r.Crashes, r.StoreErrors, r.Dropped, len(r.Lost), r.Duplicates)
+}
+
+// Run feeds random symbols to concurrent instances of the machine. Every
+// instance persists a snapshot after each token and records the intents of
+// its actions in an outbox; after a fault it is restored from the last
+// snapshot, pending intents are recovered (see dfa.Recover) and the token
+// is delivered again. Guards are evaluated, time does not pass.
+func Run(m *dfa.DFA, config Config) (*Result, error) {
+	config = defaults(m, config)
+	if len(config.Symbols) == 0 {
+		return nil, errors.New(errNoSymbols)
+	}
+	instances := make(chan int)
+	numbers := config.Only
+	if numbers == nil {
+		for i := 0; i < config.Instances; i++ {
+			numbers = append(numbers, i)
+		}
+	}
+	results := make([]*instance, len(numbers))
+	var wg sync.WaitGroup
+	for w := 0; w < config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range instances {
+				results[i] = run(m, config, numbers[i])
+			}
+		}()
+	}
+	for i := range numbers {
+		instances <- i
+	}
+	close(instances)
+	wg.Wait()
+	result := &Result{Instances: len(numbers)}
+	for _, instance := range results {
+		if instance.err != nil {
+			return nil, fmt.Errorf("instance %s: %w", instance.key, instance.err)
+		}
+		result.Tokens += instance.tokens
+		result.Transitions += instance.transitions
+		result.Crashes += instance.crashes
+		result.StoreErrors += instance.storeErrors
+		result.Dropped += instance.dropped
+		result.Duplicates += instance.outbox.duplicates()
+		if instance.lost {
+			result.Lost = append(result.Lost, instance.key)
+		}
+	}
+	return result, nil
+}
+
+// defaults fills in the zero values of the config.
+func defaults(m *dfa.DFA, config Config) Config {
+	if config.Instances <= 0 {
+		config.Instances = 100
+	}
+	if config.Tokens <= 0 {
+		config.Tokens = 100
+	}
+	if config.Workers <= 0 {
+		config.Workers = config.Instances
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.Symbols == nil {
+		for _, symbol := range m.GetSymbols() {
+			if symbol != dfa.AnySymbol && symbol != dfa.Completion {
+				config.Symbols = append(config.Symbols, symbol)
+			}
+		}
+	}
+	return config
+}
+
+// instance is the outcome of one instance.
+type instance struct {
+	key         string
+	outbox      *countingOutbox
+	tokens      int
+	transitions int
+	crashes     int
+	storeErrors int
+	dropped     int
+	lost        bool
+	err         error
+}
+
+// run feeds the random tokens to one instance.
+func run(m *dfa.DFA, config Config, i int) *instance {
+	random := rand.New(rand.NewSource(config.Seed + int64(i)))
+	result := &instance{key: fmt.Sprintf("instance-%d", i), outbox: newCountingOutbox()}
+	runner := newRunner(m, time.Time{}, result.outbox)
+	saved := runner.Snapshot()
+	var acknowledged []string
+	for t := 0; t < config.Tokens; t++ {
+		token := config.Symbols[random.Intn(len(config.Symbols))]
+		result.tokens++
+		delivered := false
+		for attempt := 0; attempt < config.MaxAttempts && !delivered; attempt++ {
+			runner.Vars()[seqVar] = len(acknowledged)
+			crashed, took := feed(runner, token, random.Float64() < config.PanicRate)
+			if crashed {
+				result.crashes++
+			} else if random.Float64() < config.StoreErrorRate {
+				result.storeErrors++
+				crashed = true
+			}
+			if crashed {
+				if runner, result.err = restart(m, saved, result.outbox); result.err != nil {
+					return result
+				}
+				continue
+			}
+			delivered = true
+			saved = runner.Snapshot()
+			acknowledged = append(acknowledged, token)
+			if took {
+				result.transitions++
+			}
+		}
+		if !delivered {
+			result.dropped++
+		}
+	}
+	reference := newRunner(m, time.Time{}, nil)
+	for _, token := range acknowledged {
+		reference.FeedContext(context.Background(), token)
+	}
+	result.lost = strings.Join(runner.Active(), "/") != strings.Join(reference.Active(), "/")
+	return result
+}
+
+// newRunner creates a runner whose clock does not advance.
+func newRunner(m *dfa.DFA, start time.Time, outbox dfa.Outbox) *dfa.Runner {
+	runner := dfa.NewRunner(m)
+	runner.SetClock(dfa.NewVirtualClock(start))
+	if outbox != nil {
+		runner.SetOutbox(outbox)
+	}
+	return runner
+}
+
+// feed feeds the token, panicking after a transition if crash is set. It
+// returns whether the instance crashed and whether a transition was taken;
+// errors are part of the behavior of the machine and ignored.
+func feed(runner *dfa.Runner, token string, crash bool) (crashed, took bool) {
+	if crash {
+		runner.SetTracer(crashTracer{})
+		defer runner.SetTracer(nil)
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if recovered != errCrash {
+				panic(recovered)
+			}
+			crashed = true
+		}
+	}()
+	_, took, _ = runner.FeedContext(context.Background(), token)
+	return false, took
+}
+
+// restart creates a runner restored from the snapshot and executes the
+// pending intents again.
+func restart(m *dfa.DFA, saved dfa.RunnerSnapshot, outbox *countingOutbox) (*dfa.Runner, error) {
+	runner := newRunner(m, time.Time{}, outbox)
+	if err := runner.Restore(saved); err != nil {
+		return nil, err
+	}
+	return runner, dfa.Recover(m, outbox)
+}
+
+// crashTracer panics after a transition was taken.
+type crashTracer struct{}
+
+func (crashTracer) OnStep(from, symbol, to string) { panic(errCrash) }
+func (crashTracer) OnReject(state, symbol string)  {}
+func (crashTracer) OnAccept(path []string)         {}
+
+// countingOutbox is an in-memory outbox counting how often the intents of
+// a transition for the same token were marked done.
+type countingOutbox struct {
+	mu      sync.Mutex
+	next    int
+	pending map[string]dfa.Intent
+	order   []string
+	done    map[string]int
+}
+
+func newCountingOutbox() *countingOutbox {
+	return &countingOutbox{pending: make(map[string]dfa.Intent), done: make(map[string]int)}
+}
+
+func (o *countingOutbox) Add(intent dfa.Intent) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	intent.ID = fmt.Sprint(o.next)
+	o.next++
+	o.pending[intent.ID] = intent
+	o.order = append(o.order, intent.ID)
+	return intent.ID, nil
+}
+
+func (o *countingOutbox) Done(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	intent, ok := o.pending[id]
+	if !ok {
+		return nil
+	}
+	delete(o.pending, id)
+	o.done[fmt.Sprintf("%v/%s/%s", intent.Vars[seqVar], intent.From, intent.Transition)]++
+	return nil
+}
+
+func (o *countingOutbox) Pending() ([]dfa.Intent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var pending []dfa.Intent
+	for _, id := range o.order {
+		if intent, ok := o.pending[id]; ok {
+			pending = append(pending, intent)
+		}
+	}
+	return pending, nil
+}
+
+// duplicates counts the executions beyond the first one.
+func (o *countingOutbox) duplicates() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	duplicates := 0
+	for _, count := range o.done {
+		duplicates += count - 1
+	}
+	return duplicates
+}
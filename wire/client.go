@@ -0,0 +1,74 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+const errMalformedResponse = "malformed response"
+
+// Client drives one remote session. It is safe for concurrent use,
+// requests are executed one after another.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// Dial connects to a server, network being "tcp" or "unix".
+func Dial(network, address string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient creates a client on an established connection.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		buf:    make([]byte, binary.MaxVarintLen64),
+	}
+}
+
+// Step sends a symbol ID and returns the status and the current state ID.
+func (c *Client) Step(symbolID uint64) (Status, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := binary.PutUvarint(c.buf, symbolID)
+	if _, err := c.conn.Write(c.buf[:n]); err != nil {
+		return 0, 0, err
+	}
+	return readResponse(c.reader)
+}
+
+// Reset resets the session to the start state and returns its ID.
+func (c *Client) Reset() (uint64, error) {
+	_, id, err := c.Step(resetID)
+	return id, err
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// readResponse reads one response from the reader.
+func readResponse(r io.ByteReader) (Status, uint64, error) {
+	status, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	if Status(status) > StatusReleased {
+		return 0, 0, errors.New(errMalformedResponse)
+	}
+	id, err := binary.ReadUvarint(r)
+	return Status(status), id, err
+}
@@ -0,0 +1,75 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// MuxClient drives many remote sessions over one multiplexed connection
+// (see Server.ServeMux). Sessions are identified by IDs chosen by the
+// caller and start at the start state on their first request. It is safe
+// for concurrent use, requests are executed one after another.
+type MuxClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// DialMux connects to a server serving multiplexed connections, network
+// being "tcp" or "unix".
+func DialMux(network, address string) (*MuxClient, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewMuxClient(conn), nil
+}
+
+// NewMuxClient creates a multiplexing client on an established connection.
+func NewMuxClient(conn net.Conn) *MuxClient {
+	return &MuxClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		buf:    make([]byte, 2*binary.MaxVarintLen64),
+	}
+}
+
+// Step sends a symbol ID for the session and returns the status and the
+// current state ID.
+func (c *MuxClient) Step(session, symbolID uint64) (Status, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := binary.PutUvarint(c.buf, session<<1)
+	n += binary.PutUvarint(c.buf[n:], symbolID)
+	if _, err := c.conn.Write(c.buf[:n]); err != nil {
+		return 0, 0, err
+	}
+	return readResponse(c.reader)
+}
+
+// Reset resets the session to the start state and returns its ID.
+func (c *MuxClient) Reset(session uint64) (uint64, error) {
+	_, id, err := c.Step(session, resetID)
+	return id, err
+}
+
+// Release releases the session on the server, its ID starts a new session
+// at the start state afterwards.
+func (c *MuxClient) Release(session uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := binary.PutUvarint(c.buf, session<<1|releaseFlag)
+	if _, err := c.conn.Write(c.buf[:n]); err != nil {
+		return err
+	}
+	_, _, err := readResponse(c.reader)
+	return err
+}
+
+// Close closes the connection and with it all its sessions.
+func (c *MuxClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,197 @@
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// Server serves sessions of one machine, every connection being a session
+// that starts at the start state of the machine.
+type Server struct {
+	machine   *dfa.DFA
+	table     *Table
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+}
+
+// NewServer creates a new server for the machine.
+func NewServer(m *dfa.DFA) *Server {
+	return &Server{
+		machine:   m,
+		table:     NewTable(m),
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[net.Conn]bool),
+	}
+}
+
+// Table returns the ID table the server uses.
+func (s *Server) Table() *Table {
+	return s.table
+}
+
+// Serve accepts connections on the listener (TCP or Unix socket) until it
+// fails or the server is closed, in which case nil is returned.
+func (s *Server) Serve(l net.Listener) error {
+	return s.serve(l, s.handle)
+}
+
+// ServeMux accepts multiplexed connections like Serve, every connection
+// carrying many sessions (see MuxClient).
+func (s *Server) ServeMux(l net.Listener) error {
+	return s.serve(l, s.handleMux)
+}
+
+// serve accepts connections and handles each in its own goroutine.
+func (s *Server) serve(l net.Listener, handle func(conn net.Conn)) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.New(errServerClosed)
+	}
+	s.listeners[l] = true
+	s.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+		go handle(conn)
+	}
+}
+
+// Close closes all listeners and open sessions.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// handle executes the requests of one session.
+func (s *Server) handle(conn net.Conn) {
+	defer s.release(conn)
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	current := s.machine.GetStart()
+	buf := make([]byte, 1+binary.MaxVarintLen64)
+	for {
+		id, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return
+		}
+		status := s.step(&current, id)
+		if err := s.respond(reader, writer, buf, status, current); err != nil {
+			return
+		}
+	}
+}
+
+// handleMux executes the requests of the sessions of a multiplexed
+// connection, sessions start at the start state on their first request.
+func (s *Server) handleMux(conn net.Conn) {
+	defer s.release(conn)
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	sessions := make(map[uint64]string)
+	buf := make([]byte, 1+binary.MaxVarintLen64)
+	for {
+		header, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return
+		}
+		session := header >> 1
+		if header&releaseFlag != 0 {
+			delete(sessions, session)
+			if err := s.respond(reader, writer, buf, StatusReleased, ""); err != nil {
+				return
+			}
+			continue
+		}
+		id, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return
+		}
+		current, ok := sessions[session]
+		if !ok {
+			current = s.machine.GetStart()
+		}
+		status := s.step(&current, id)
+		sessions[session] = current
+		if err := s.respond(reader, writer, buf, status, current); err != nil {
+			return
+		}
+	}
+}
+
+// respond writes the status and the ID of the current state, StatusNoState
+// if the state is not part of the table. The response is only flushed if no
+// further requests are already buffered.
+func (s *Server) respond(reader *bufio.Reader, writer *bufio.Writer, buf []byte, status Status,
+	current string) error {
+	stateID, err := s.table.StateID(current)
+	if err != nil && status != StatusReleased {
+		status = StatusNoState
+	}
+	buf[0] = byte(status)
+	n := binary.PutUvarint(buf[1:], stateID)
+	if _, err := writer.Write(buf[:1+n]); err != nil {
+		return err
+	}
+	if reader.Buffered() == 0 {
+		return writer.Flush()
+	}
+	return nil
+}
+
+// release forgets and closes a connection once it is handled.
+func (s *Server) release(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// step executes one request on the session state.
+func (s *Server) step(current *string, id uint64) Status {
+	if id == resetID {
+		*current = s.machine.GetStart()
+		return StatusReset
+	}
+	symbol, ok := s.table.Symbol(id)
+	if !ok {
+		return StatusUnknownSymbol
+	}
+	next, ok, err := s.machine.Step(*current, symbol)
+	if err != nil || !ok {
+		return StatusRejected
+	}
+	*current = next
+	return StatusMoved
+}
@@ -0,0 +1,117 @@
+// Package wire implements a compact binary protocol to drive a DFA over
+// a network connection.
+//
+// Every request is a single uvarint: 0 resets the session to the start
+// state, every other value is the ID of a symbol. Every response is a
+// status byte followed by the uvarint ID of the current state. Symbol and
+// state IDs are assigned by a Table, which both ends build from the same
+// machine.
+//
+// Multiplexed connections (see Server.ServeMux and MuxClient) carry many
+// sessions: every request starts with a uvarint header holding the session
+// ID shifted left by one. If the lowest bit is set the session is released
+// and nothing follows, otherwise the request follows as above. Responses
+// are the same and come in the order of the requests.
+package wire
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/breskos/gopher-state/dfa"
+)
+
+// Status is the outcome of a request.
+type Status byte
+
+const (
+	// StatusMoved reports that the symbol lead to a transition
+	StatusMoved Status = iota
+	// StatusRejected reports that the state has no transition for the symbol
+	StatusRejected
+	// StatusUnknownSymbol reports a symbol ID that is not part of the table
+	StatusUnknownSymbol
+	// StatusReset reports that the session was reset to the start state
+	StatusReset
+	// StatusNoState reports that the current state is not part of the
+	// table, e.g. because the machine has no start state; the state ID is 0
+	// then and does not identify a state
+	StatusNoState
+	// StatusReleased reports that a session of a multiplexed connection was
+	// released; the state ID is 0
+	StatusReleased
+)
+
+const (
+	// resetID is the request value resetting a session
+	resetID = 0
+	// releaseFlag marks the header of a multiplexed request releasing its
+	// session
+	releaseFlag = 1
+)
+
+const (
+	errUnknownName  = "name not part of the table"
+	errServerClosed = "server closed"
+)
+
+// Table assigns IDs to the symbols and states of a machine. Symbol IDs
+// start at 1, state IDs at 0, both in sorted order of the names.
+type Table struct {
+	symbols  []string
+	states   []string
+	symbolID map[string]uint64
+	stateID  map[string]uint64
+}
+
+// NewTable builds the ID table of a machine.
+func NewTable(m *dfa.DFA) *Table {
+	t := &Table{
+		symbols:  m.GetSymbols(),
+		symbolID: make(map[string]uint64),
+		stateID:  make(map[string]uint64),
+	}
+	sort.Strings(t.symbols)
+	for i, symbol := range t.symbols {
+		t.symbolID[symbol] = uint64(i + 1)
+	}
+	for _, state := range m.GetStates() {
+		t.states = append(t.states, state.Name)
+	}
+	for i, name := range t.states {
+		t.stateID[name] = uint64(i)
+	}
+	return t
+}
+
+// SymbolID returns the ID of a symbol.
+func (t *Table) SymbolID(symbol string) (uint64, error) {
+	if id, ok := t.symbolID[symbol]; ok {
+		return id, nil
+	}
+	return 0, errors.New(errUnknownName)
+}
+
+// Symbol returns the symbol of an ID.
+func (t *Table) Symbol(id uint64) (string, bool) {
+	if id == resetID || id > uint64(len(t.symbols)) {
+		return "", false
+	}
+	return t.symbols[id-1], true
+}
+
+// StateID returns the ID of a state.
+func (t *Table) StateID(state string) (uint64, error) {
+	if id, ok := t.stateID[state]; ok {
+		return id, nil
+	}
+	return 0, errors.New(errUnknownName)
+}
+
+// State returns the name of a state ID.
+func (t *Table) State(id uint64) (string, bool) {
+	if id >= uint64(len(t.states)) {
+		return "", false
+	}
+	return t.states[id], true
+}